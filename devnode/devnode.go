@@ -0,0 +1,95 @@
+// Package devnode implements an in-process stand-in for an Ethereum node. It holds
+// the state given by a genesis allocation and answers the JSON-RPC calls the oracle
+// makes (eth_getBlockByNumber, eth_getProof, eth_getCode, debug_dbGet), so that the witness can be
+// generated for exactly the trie shapes a test needs without starting geth.
+package devnode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/state"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/trie"
+)
+
+// Node is a chain which consists of the genesis block only. Its state never changes,
+// so eth_getProof and eth_getCode answer with the genesis state for any block.
+type Node struct {
+	header  types.Header
+	alloc   core.GenesisAlloc
+	trie    *trie.Trie
+	storage map[common.Address]*trie.Trie
+	nodes   map[common.Hash][]byte // the trie nodes by hash, for debug_dbGet
+
+	lock sync.Mutex // the tries cache the hashes of the nodes when proving
+}
+
+// New builds the account and storage tries for alloc.
+func New(alloc core.GenesisAlloc) (*Node, error) {
+	n := &Node{
+		alloc: alloc,
+		nodes: make(map[common.Hash][]byte),
+	}
+	// The tries are never committed, so the database (which would consult the oracle)
+	// is never used.
+	var err error
+	if n.trie, n.storage, err = state.AllocTries(alloc, &trie.Database{}); err != nil {
+		return nil, err
+	}
+
+	// The proofs of all keys contain all nodes of the tries.
+	for addr, account := range alloc {
+		n.addNodes(n.trie, crypto.Keccak256(addr[:]))
+		for key := range account.Storage {
+			n.addNodes(n.storage[addr], crypto.Keccak256(key[:]))
+		}
+	}
+
+	n.header = types.Header{
+		UncleHash:   types.EmptyUncleHash,
+		Root:        n.trie.Hash(),
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: types.EmptyRootHash,
+		Difficulty:  big.NewInt(1),
+		Number:      new(big.Int),
+		GasLimit:    params.GenesisGasLimit,
+		Extra:       []byte{},
+	}
+	return n, nil
+}
+
+// NewFromJSON is New for the alloc in the geth genesis format:
+// {"0x...": {"balance": "0x...", "nonce": "0x...", "code": "0x...", "storage": {...}}}.
+func NewFromJSON(data []byte) (*Node, error) {
+	var alloc core.GenesisAlloc
+	if err := json.Unmarshal(data, &alloc); err != nil {
+		return nil, fmt.Errorf("invalid genesis alloc: %v", err)
+	}
+	return New(alloc)
+}
+
+// addNodes adds the nodes on the path to key to n.nodes.
+func (n *Node) addNodes(t *trie.Trie, key []byte) {
+	var nodes proofList
+	t.Prove(key, 0, &nodes)
+	for _, enc := range nodes {
+		n.nodes[crypto.Keccak256Hash(enc)] = enc
+	}
+}
+
+// Header returns the genesis block header.
+func (n *Node) Header() types.Header {
+	return n.header
+}
+
+// Root returns the state root.
+func (n *Node) Root() common.Hash {
+	return n.header.Root
+}
@@ -0,0 +1,255 @@
+package devnode
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/oracle"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/state"
+)
+
+const allocJSON = `{
+	"0x0000000000000000000000000000000000000001": {"balance": "0x1"},
+	"0x0000000000000000000000000000000000000002": {"balance": "0x1"},
+	"0x50efbf12580138bc623c95757286df4e24eb81c9": {
+		"balance": "0xde0b6b3a7640000",
+		"nonce": "0x3",
+		"code": "0x6001600055",
+		"storage": {
+			"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000011",
+			"0x0000000000000000000000000000000000000000000000000000000000000002": "0x0000000000000000000000000000000000000000000000000000000000000022"
+		}
+	}
+}`
+
+func newStateDB(t *testing.T, n *Node) (*state.StateDB, *oracle.RPCOracle) {
+	server := httptest.NewServer(n)
+	t.Cleanup(server.Close)
+
+	o := oracle.NewRPCOracle(server.URL)
+	o.CacheMode = oracle.Passthrough
+	header, err := o.PrefetchBlock(oracle.BlockNumberRef(big.NewInt(0)), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := n.Header()
+	if header.Root != n.Root() || header.Hash() != genesis.Hash() {
+		t.Fatalf("header %s (root %s) differs from the genesis header %s", header.Hash(), header.Root, genesis.Hash())
+	}
+	database, err := state.NewDatabase(header, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, err := state.New(header.Root, database, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statedb, o
+}
+
+func TestStateFromAlloc(t *testing.T) {
+	n, err := NewFromJSON([]byte(allocJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, _ := newStateDB(t, n)
+
+	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
+	if b := statedb.GetBalance(addr); b.Cmp(big.NewInt(1000000000000000000)) != 0 {
+		t.Errorf("wrong balance %v", b)
+	}
+	if nonce := statedb.GetNonce(addr); nonce != 3 {
+		t.Errorf("wrong nonce %d", nonce)
+	}
+	if code := statedb.GetCode(addr); !bytes.Equal(code, common.FromHex("0x6001600055")) {
+		t.Errorf("wrong code %x", code)
+	}
+	if v := statedb.GetState(addr, common.HexToHash("0x2")); v != common.HexToHash("0x22") {
+		t.Errorf("wrong storage value %s", v)
+	}
+	if v := statedb.GetState(addr, common.HexToHash("0x3")); v != (common.Hash{}) {
+		t.Errorf("wrong value %s for an empty slot", v)
+	}
+	if statedb.Exist(common.HexToAddress("0x3")) {
+		t.Error("account not in the alloc exists")
+	}
+	if err := statedb.Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The state is unchanged, so the root is the one from the header.
+	if root := statedb.IntermediateRoot(false); root != n.Root() {
+		t.Errorf("state root %s differs from the genesis root %s", root, n.Root())
+	}
+	// The offline state of the same alloc has the same root.
+	offline, err := state.NewFromAlloc(n.alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root := offline.IntermediateRoot(false); root != n.Root() {
+		t.Errorf("state root %s of NewFromAlloc differs from the genesis root %s", root, n.Root())
+	}
+}
+
+func TestBatchedStorageProofs(t *testing.T) {
+	n, err := NewFromJSON([]byte(allocJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, o := newStateDB(t, n)
+
+	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
+	proofs, err := o.PrefetchStorageBatch(big.NewInt(0), []oracle.StorageKeys{
+		{Address: addr, Keys: []common.Hash{common.HexToHash("0x1"), common.HexToHash("0x2")}},
+		{Address: common.HexToAddress("0x1"), Keys: []common.Hash{common.HexToHash("0x1")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(proofs[0][0]) == 0 || len(proofs[0][1]) == 0 {
+		t.Errorf("missing storage proofs %v", proofs[0])
+	}
+	if len(proofs[1][0]) != 0 {
+		t.Errorf("storage proof %v for an empty storage trie", proofs[1][0])
+	}
+}
+
+func TestProofOfMissingAccount(t *testing.T) {
+	n, err := NewFromJSON([]byte(allocJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := []json.RawMessage{json.RawMessage(`"0x0000000000000000000000000000000000000003"`), json.RawMessage(`["0x1"]`)}
+	res, rpcErr := n.getProof(params)
+	if rpcErr != nil {
+		t.Fatal(rpcErr)
+	}
+	// Like geth, the storage hash of a missing account is the empty root.
+	if h := res.(oracle.AccountResult).StorageHash; h != types.EmptyRootHash {
+		t.Fatalf("storage hash %s, expected the empty root", h)
+	}
+}
+
+func TestDeleteWithUnknownSibling(t *testing.T) {
+	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
+	// The root of the storage trie is a branch with the leaves of 0x2 and 0x3. Deleting
+	// 0x2 turns the leaf of 0x3 into the root, but the leaf is not part of the proof of 0x2.
+	storage := map[common.Hash]common.Hash{
+		common.HexToHash("0x3"): common.HexToHash("0x33"),
+	}
+	after, err := New(core.GenesisAlloc{addr: {Balance: big.NewInt(1), Storage: storage}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage[common.HexToHash("0x2")] = common.HexToHash("0x22")
+	n, err := New(core.GenesisAlloc{addr: {Balance: big.NewInt(1), Storage: storage}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, method := range []string{oracle.DefaultNodeMethod, ""} {
+		statedb, o := newStateDB(t, n)
+		o.NodeMethod = method
+		statedb.GetState(addr, common.HexToHash("0x2"))
+		statedb.SetState(addr, common.HexToHash("0x2"), common.Hash{})
+		root := statedb.IntermediateRoot(false)
+
+		if method == "" {
+			// The unresolved sibling is taken for a branch, so the root is wrong.
+			if root == after.Root() {
+				t.Error("deletion succeeded without fetching the sibling")
+			}
+			continue
+		}
+		if err := statedb.Error(); err != nil {
+			t.Fatal(err)
+		}
+		if root != after.Root() {
+			t.Errorf("state root %s after the deletion, expected %s", root, after.Root())
+		}
+	}
+}
+
+func TestSingleLeafTrie(t *testing.T) {
+	addr := common.HexToAddress("0xaaaccf12580138bc2bbceeeaa111df4e42ab81ab")
+	n, err := New(core.GenesisAlloc{addr: {Balance: big.NewInt(5)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, _ := newStateDB(t, n)
+
+	if b := statedb.GetBalance(addr); b.Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("wrong balance %v", b)
+	}
+	if statedb.Exist(common.HexToAddress("0x1")) {
+		t.Error("account not in the alloc exists")
+	}
+	leaf, err := statedb.GetTrieRootElement()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaf) == 0 {
+		t.Error("no root element")
+	}
+}
+
+func TestUnknownBlock(t *testing.T) {
+	n, err := New(core.GenesisAlloc{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(n)
+	defer server.Close()
+
+	o := oracle.NewRPCOracle(server.URL)
+	o.CacheMode = oracle.Passthrough
+	if _, err := o.PrefetchBlock(oracle.BlockNumberRef(big.NewInt(1)), true, nil); err == nil {
+		t.Fatal("expected an error for a block which doesn't exist")
+	}
+	var notFound *oracle.BlockNotFoundError
+	if _, err := o.PrefetchAccount(big.NewInt(1), common.HexToAddress("0x1"), nil); !errors.As(err, &notFound) {
+		t.Fatalf("expected a BlockNotFoundError, got %v", err)
+	}
+}
+
+func TestBlockByTagAndHash(t *testing.T) {
+	n, err := NewFromJSON([]byte(allocJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(n)
+	defer server.Close()
+
+	genesis := n.Header()
+	for _, ref := range []oracle.BlockRef{
+		oracle.BlockTagRef(oracle.FinalizedBlock),
+		oracle.BlockHashRef(genesis.Hash(), true),
+	} {
+		o := oracle.NewRPCOracle(server.URL)
+		o.CacheMode = oracle.Passthrough
+		header, err := o.PrefetchBlock(ref, true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Hash() != genesis.Hash() {
+			t.Errorf("%s: got block %s, expected the genesis block", ref, header.Hash())
+		}
+		// The proofs are requested for the pinned block.
+		if _, err := o.PrefetchAccount(header.Number, common.HexToAddress("0x1"), nil); err != nil {
+			t.Error(err)
+		}
+	}
+
+	o := oracle.NewRPCOracle(server.URL)
+	o.CacheMode = oracle.Passthrough
+	if _, err := o.PrefetchBlock(oracle.BlockHashRef(common.HexToHash("0x1"), false), true, nil); err == nil {
+		t.Fatal("expected an error for an unknown block hash")
+	}
+}
@@ -0,0 +1,264 @@
+package devnode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/oracle"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/trie"
+)
+
+type request struct {
+	Jsonrpc string            `json:"jsonrpc"`
+	Id      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// The JSON-RPC error codes used by geth.
+const (
+	parseErrorCode     = -32700
+	methodNotFoundCode = -32601
+	invalidParamsCode  = -32602
+	defaultErrorCode   = -32000
+)
+
+// ServeHTTP answers a single JSON-RPC request or a batch of them.
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	if body = bytes.TrimSpace(body); bytes.HasPrefix(body, []byte("[")) {
+		var reqs []request
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			json.NewEncoder(w).Encode(errorResponse(nil, &rpcError{parseErrorCode, err.Error()}))
+			return
+		}
+		resps := make([]response, len(reqs))
+		for i, req := range reqs {
+			resps[i] = n.handle(req)
+		}
+		json.NewEncoder(w).Encode(resps)
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		json.NewEncoder(w).Encode(errorResponse(nil, &rpcError{parseErrorCode, err.Error()}))
+		return
+	}
+	json.NewEncoder(w).Encode(n.handle(req))
+}
+
+func errorResponse(id json.RawMessage, err *rpcError) response {
+	return response{Jsonrpc: "2.0", Id: id, Error: err}
+}
+
+func (n *Node) handle(req request) response {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	var result interface{}
+	var err *rpcError
+	switch req.Method {
+	case "eth_getBlockByNumber":
+		result, err = n.getBlockByNumber(req.Params)
+	case "eth_getBlockByHash":
+		result, err = n.getBlockByHash(req.Params)
+	case "eth_getProof":
+		result, err = n.getProof(req.Params)
+	case "eth_getCode":
+		result, err = n.getCode(req.Params)
+	case "debug_dbGet":
+		result, err = n.dbGet(req.Params)
+	default:
+		err = &rpcError{methodNotFoundCode, fmt.Sprintf("the method %s does not exist/is not available", req.Method)}
+	}
+	if err != nil {
+		return errorResponse(req.Id, err)
+	}
+	return response{Jsonrpc: "2.0", Id: req.Id, Result: result}
+}
+
+// param decodes the i-th parameter into v.
+func param(params []json.RawMessage, i int, v interface{}) *rpcError {
+	if i >= len(params) {
+		return &rpcError{invalidParamsCode, fmt.Sprintf("missing value for required argument %d", i)}
+	}
+	if err := json.Unmarshal(params[i], v); err != nil {
+		return &rpcError{invalidParamsCode, fmt.Sprintf("invalid argument %d: %v", i, err)}
+	}
+	return nil
+}
+
+// getBlockByNumber returns the genesis header for block 0 (or a block tag) and null
+// for the blocks that don't exist.
+func (n *Node) getBlockByNumber(params []json.RawMessage) (interface{}, *rpcError) {
+	var number string
+	if err := param(params, 0, &number); err != nil {
+		return nil, err
+	}
+	switch number {
+	case "earliest", "latest", "pending", "safe", "finalized":
+	default:
+		num, err := hexutil.DecodeBig(number)
+		if err != nil {
+			return nil, &rpcError{invalidParamsCode, fmt.Sprintf("invalid argument 0: %v", err)}
+		}
+		if num.Sign() != 0 {
+			return nil, nil
+		}
+	}
+	return n.block(), nil
+}
+
+// getBlockByHash returns the genesis header for its hash and null for the other hashes.
+func (n *Node) getBlockByHash(params []json.RawMessage) (interface{}, *rpcError) {
+	var hash common.Hash
+	if err := param(params, 0, &hash); err != nil {
+		return nil, err
+	}
+	if hash != n.header.Hash() {
+		return nil, nil
+	}
+	return n.block(), nil
+}
+
+// block returns the genesis block in the format of eth_getBlockByNumber.
+func (n *Node) block() map[string]interface{} {
+	h := n.header
+	return map[string]interface{}{
+		"number":           (*hexutil.Big)(h.Number),
+		"hash":             h.Hash(),
+		"parentHash":       h.ParentHash,
+		"nonce":            h.Nonce,
+		"mixHash":          h.MixDigest,
+		"sha3Uncles":       h.UncleHash,
+		"logsBloom":        h.Bloom,
+		"stateRoot":        h.Root,
+		"miner":            h.Coinbase,
+		"difficulty":       (*hexutil.Big)(h.Difficulty),
+		"extraData":        hexutil.Bytes(h.Extra),
+		"gasLimit":         hexutil.Uint64(h.GasLimit),
+		"gasUsed":          hexutil.Uint64(h.GasUsed),
+		"timestamp":        hexutil.Uint64(h.Time),
+		"transactionsRoot": h.TxHash,
+		"receiptsRoot":     h.ReceiptHash,
+		"transactions":     []interface{}{},
+		"uncles":           []common.Hash{},
+	}
+}
+
+func (n *Node) getProof(params []json.RawMessage) (interface{}, *rpcError) {
+	var addr common.Address
+	var keys []string
+	if err := param(params, 0, &addr); err != nil {
+		return nil, err
+	}
+	if err := param(params, 1, &keys); err != nil {
+		return nil, err
+	}
+
+	res := oracle.AccountResult{
+		Address:      addr,
+		AccountProof: prove(n.trie, crypto.Keccak256(addr[:])),
+		Balance:      new(hexutil.Big),
+		StorageHash:  types.EmptyRootHash, // geth returns the empty root for a missing account
+		StorageProof: []oracle.StorageResult{},
+	}
+	account, exists := n.alloc[addr]
+	st := n.storage[addr]
+	if exists {
+		res.Nonce = hexutil.Uint64(account.Nonce)
+		if account.Balance != nil {
+			res.Balance = (*hexutil.Big)(account.Balance)
+		}
+		res.CodeHash = crypto.Keccak256Hash(account.Code)
+		res.StorageHash = st.Hash()
+	}
+	for _, k := range keys {
+		key := common.HexToHash(k)
+		sr := oracle.StorageResult{Key: k, Value: new(hexutil.Big), Proof: []string{}}
+		if exists {
+			sr.Value = (*hexutil.Big)(account.Storage[key].Big())
+			sr.Proof = prove(st, crypto.Keccak256(key[:]))
+		}
+		res.StorageProof = append(res.StorageProof, sr)
+	}
+	return res, nil
+}
+
+func (n *Node) getCode(params []json.RawMessage) (interface{}, *rpcError) {
+	var addr common.Address
+	if err := param(params, 0, &addr); err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(n.alloc[addr].Code), nil
+}
+
+// dbGet returns the trie node with the given hash, like geth's debug_dbGet does for the
+// keys of the hash-based trie database.
+func (n *Node) dbGet(params []json.RawMessage) (interface{}, *rpcError) {
+	var key hexutil.Bytes
+	if err := param(params, 0, &key); err != nil {
+		return nil, err
+	}
+	node, ok := n.nodes[common.BytesToHash(key)]
+	if !ok || len(key) != common.HashLength {
+		return nil, &rpcError{defaultErrorCode, "leveldb: not found"}
+	}
+	return hexutil.Bytes(node), nil
+}
+
+type proofList [][]byte
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+func (n *proofList) Delete(key []byte) error {
+	panic("not supported")
+}
+
+// prove returns the proof for key the way eth_getProof does: trie.Prove returns all
+// nodes on the path, but the nodes shorter than 32 bytes are embedded in their parents
+// and are not part of the proof (except for the root).
+func prove(t *trie.Trie, key []byte) []string {
+	var nodes proofList
+	t.Prove(key, 0, &nodes)
+	proof := []string{}
+	for i, enc := range nodes {
+		if i == 0 || len(enc) >= 32 {
+			proof = append(proof, hexutil.Encode(enc))
+		}
+	}
+	return proof
+}
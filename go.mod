@@ -4,7 +4,9 @@ go 1.16
 
 require (
 	github.com/VictoriaMetrics/fastcache v1.6.0 // indirect
-	github.com/ethereum/go-ethereum v1.10.8 // indirect
+	github.com/ethereum/go-ethereum v1.10.8
+	github.com/gorilla/websocket v1.4.2
 	github.com/holiman/uint256 v1.2.0 // indirect
-	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2 // indirect
+	github.com/syndtr/goleveldb v1.0.1-0.20210305035536-64b5b1c73954
+	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
 )
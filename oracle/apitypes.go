@@ -54,6 +54,14 @@ type Header struct {
 	MixDigest   *common.Hash      `json:"mixHash"`
 	Nonce       *types.BlockNonce `json:"nonce"`
 	BaseFee     *hexutil.Big      `json:"baseFeePerGas" rlp:"optional"`
+	// Shanghai, Cancun and Prague
+	WithdrawalsRoot  *common.Hash    `json:"withdrawalsRoot"`
+	BlobGasUsed      *hexutil.Uint64 `json:"blobGasUsed"`
+	ExcessBlobGas    *hexutil.Uint64 `json:"excessBlobGas"`
+	ParentBeaconRoot *common.Hash    `json:"parentBeaconBlockRoot"`
+	RequestsHash     *common.Hash    `json:"requestsHash"`
+	// the hash returned by the node, to be compared with the hash of the header
+	Hash *common.Hash `json:"hash"`
 	// transactions
 	Transactions []SendTxArgs `json:"transactions"`
 }
@@ -85,6 +93,39 @@ func (dec *Header) ToHeader() types.Header {
 	return h
 }
 
+// ToFullHeader is ToHeader with the fields added after London.
+func (dec *Header) ToFullHeader() FullHeader {
+	h := dec.ToHeader()
+	full := FullHeader{
+		ParentHash:       h.ParentHash,
+		UncleHash:        h.UncleHash,
+		Coinbase:         h.Coinbase,
+		Root:             h.Root,
+		TxHash:           h.TxHash,
+		ReceiptHash:      h.ReceiptHash,
+		Bloom:            h.Bloom,
+		Difficulty:       h.Difficulty,
+		Number:           h.Number,
+		GasLimit:         h.GasLimit,
+		GasUsed:          h.GasUsed,
+		Time:             h.Time,
+		Extra:            h.Extra,
+		MixDigest:        h.MixDigest,
+		Nonce:            h.Nonce,
+		BaseFee:          h.BaseFee,
+		WithdrawalsRoot:  dec.WithdrawalsRoot,
+		ParentBeaconRoot: dec.ParentBeaconRoot,
+		RequestsHash:     dec.RequestsHash,
+	}
+	if dec.BlobGasUsed != nil {
+		full.BlobGasUsed = (*uint64)(dec.BlobGasUsed)
+	}
+	if dec.ExcessBlobGas != nil {
+		full.ExcessBlobGas = (*uint64)(dec.ExcessBlobGas)
+	}
+	return full
+}
+
 // ToTransaction converts the arguments to a transaction.
 func (args *SendTxArgs) ToTransaction() *types.Transaction {
 	// Add the To-field, if specified
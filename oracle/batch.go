@@ -0,0 +1,150 @@
+package oracle
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// StorageKeys lists the storage keys of an account for which the proofs are to be fetched.
+type StorageKeys struct {
+	Address common.Address
+	Keys    []common.Hash
+}
+
+// PrefetchStorageKeys fetches the proofs for all keys of addr with a single eth_getProof call.
+// The nodes of the account proof and of all storage proofs are stored as preimages.
+// It returns the storage proofs in the order of keys, the proof is nil for the keys
+// that have already been fetched.
+func (o *RPCOracle) PrefetchStorageKeys(blockNumber *big.Int, addr common.Address, keys []common.Hash) ([][]string, error) {
+	proofs, err := o.PrefetchStorageBatch(blockNumber, []StorageKeys{{Address: addr, Keys: keys}})
+	if err != nil {
+		return nil, err
+	}
+	return proofs[0], nil
+}
+
+// PrefetchStorageBatch is PrefetchStorageKeys for several accounts, the eth_getProof calls
+// for all accounts are sent as a single JSON-RPC batch.
+func (o *RPCOracle) PrefetchStorageBatch(blockNumber *big.Int, accounts []StorageKeys) ([][][]string, error) {
+	proofs := make([][][]string, len(accounts))
+
+	var reqs []jsonreq
+	var reqAccount []int  // the index of the account for each of the requests
+	var reqKeyPos [][]int // the positions of the requested keys in accounts[i].Keys
+	var cacheKeys []string
+	for i, acc := range accounts {
+		proofs[i] = make([][]string, len(acc.Keys))
+
+		var keys []common.Hash
+		var pos []int
+		for j, skey := range acc.Keys {
+			key := fmt.Sprintf("proof_%d_%s_%s", blockNumber, acc.Address, skey)
+			if o.isCached(key) {
+				continue
+			}
+			cacheKeys = append(cacheKeys, key)
+			keys = append(keys, skey)
+			pos = append(pos, j)
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		o.setUnhash(crypto.Keccak256Hash(acc.Address[:]), acc.Address)
+		reqs = append(reqs, getProofRequest(uint64(len(reqs)+1), o.blockRef(blockNumber), acc.Address, keys))
+		reqAccount = append(reqAccount, i)
+		reqKeyPos = append(reqKeyPos, pos)
+	}
+	if len(reqs) == 0 {
+		return proofs, nil
+	}
+
+	results, err := o.getProofs(reqs)
+	if err != nil {
+		return nil, err
+	}
+	newPreimages := make(map[common.Hash][]byte)
+	accountProofs := make(map[common.Address][]string)
+	for r, res := range results {
+		acc := accounts[reqAccount[r]]
+		if len(res.StorageProof) != len(reqKeyPos[r]) {
+			return nil, &MalformedResultError{Method: "eth_getProof", Err: fmt.Errorf("%d storage proofs for %s, %d requested",
+				len(res.StorageProof), acc.Address, len(reqKeyPos[r]))}
+		}
+		keys := make([]common.Hash, len(reqKeyPos[r]))
+		for k, pos := range reqKeyPos[r] {
+			keys[k] = acc.Keys[pos]
+		}
+		if err := o.verifyProof(blockNumber, acc.Address, keys, &res); err != nil {
+			return nil, err
+		}
+		if err := addProofPreimages(newPreimages, res.AccountProof); err != nil {
+			return nil, err
+		}
+		accountProofs[acc.Address] = res.AccountProof
+		for k, sp := range res.StorageProof {
+			proofs[reqAccount[r]][reqKeyPos[r][k]] = sp.Proof
+			if err := addProofPreimages(newPreimages, sp.Proof); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := o.putPreimages(newPreimages); err != nil {
+		return nil, err
+	}
+	o.setCached(cacheKeys...)
+	for addr, proof := range accountProofs {
+		o.setAccountProof(fmt.Sprintf("proof_%d_%s", blockNumber, addr), proof)
+	}
+
+	return proofs, nil
+}
+
+func getProofRequest(id uint64, block BlockRef, addr common.Address, keys []common.Hash) jsonreq {
+	r := jsonreq{Jsonrpc: "2.0", Method: "eth_getProof", Id: id}
+	r.Params = make([]interface{}, 3)
+	r.Params[0] = addr
+	r.Params[1] = keys
+	r.Params[2] = block
+	return r
+}
+
+// getProofs sends the eth_getProof requests and returns the results in the order of reqs.
+// A single request is sent as is, more requests are sent as a JSON-RPC batch.
+func (o *RPCOracle) getProofs(reqs []jsonreq) ([]AccountResult, error) {
+	if len(reqs) == 1 {
+		var res AccountResult
+		if err := o.call(reqs[0], &res); err != nil {
+			return nil, err
+		}
+		return []AccountResult{res}, nil
+	}
+
+	jrs, err := o.callBatch(reqs)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]AccountResult, len(reqs))
+	for i, jr := range jrs {
+		if err := jr.decode(reqs[i].Method, &results[i]); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// addProofPreimages decodes the nodes of the proof into newPreimages.
+func addProofPreimages(newPreimages map[common.Hash][]byte, proof []string) error {
+	nodes, err := proofPreimages("eth_getProof", proof)
+	if err != nil {
+		return err
+	}
+	for hash, val := range nodes {
+		newPreimages[hash] = val
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package oracle
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestPrefetchStorageKeys(t *testing.T) {
+	addr := common.HexToAddress("0xaaaccf12580138bc2bbceeeaa111df4e42ab81ff")
+	var keys []common.Hash
+	for i := 1; i <= 500; i++ {
+		keys = append(keys, common.BigToHash(big.NewInt(int64(i))))
+	}
+	s := newTestState(map[common.Address][]common.Hash{addr: keys})
+	calls := make(map[string]int)
+	server := s.server(calls)
+	defer server.Close()
+	o := testOracle(server.URL)
+
+	proofs, err := o.PrefetchStorageKeys(big.NewInt(1), addr, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls["eth_getProof"] != 1 {
+		t.Fatalf("expected one eth_getProof call, got %d", calls["eth_getProof"])
+	}
+	expected := s.getProof(addr, keys)
+	for i, key := range keys {
+		proof := proofs[i]
+		if len(proof) == 0 || proof[len(proof)-1] != expected.StorageProof[i].Proof[len(proof)-1] {
+			t.Fatalf("wrong proof for key %s: %v", key, proof)
+		}
+		leaf := common.FromHex(proof[len(proof)-1])
+		if !bytes.Equal(o.Preimage(crypto.Keccak256Hash(leaf)), leaf) {
+			t.Fatalf("proof node for key %s not stored", key)
+		}
+	}
+	if o.Preimage(s.trie.Hash()) == nil {
+		t.Fatal("account proof node not stored")
+	}
+
+	// Already fetched keys are not requested again.
+	proofs, err = o.PrefetchStorageKeys(big.NewInt(1), addr, keys[:2])
+	if err != nil || calls["eth_getProof"] != 1 || proofs[0] != nil || proofs[1] != nil {
+		t.Fatal("cached keys were fetched again")
+	}
+}
+
+func TestPrefetchStorageBatch(t *testing.T) {
+	accounts := []StorageKeys{
+		{Address: common.HexToAddress("0x1"), Keys: []common.Hash{common.HexToHash("0x11"), common.HexToHash("0x12")}},
+		{Address: common.HexToAddress("0x2"), Keys: []common.Hash{common.HexToHash("0x21")}},
+		{Address: common.HexToAddress("0x3"), Keys: []common.Hash{common.HexToHash("0x31"), common.HexToHash("0x32")}},
+	}
+	state := make(map[common.Address][]common.Hash)
+	for _, acc := range accounts {
+		state[acc.Address] = acc.Keys
+	}
+	s := newTestState(state)
+	calls := make(map[string]int)
+	server := s.server(calls)
+	defer server.Close()
+	o := testOracle(server.URL)
+
+	proofs, err := o.PrefetchStorageBatch(big.NewInt(1), accounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls["eth_getProof"] != 1 {
+		t.Fatalf("expected one batch call, got %d", calls["eth_getProof"])
+	}
+	for i, acc := range accounts {
+		expected := s.getProof(acc.Address, acc.Keys)
+		for j, key := range acc.Keys {
+			if len(proofs[i][j]) != len(expected.StorageProof[j].Proof) {
+				t.Fatalf("wrong proof for %s key %s: %v", acc.Address, key, proofs[i][j])
+			}
+			for k := range proofs[i][j] {
+				if proofs[i][j][k] != expected.StorageProof[j].Proof[k] {
+					t.Fatalf("wrong proof for %s key %s: %v", acc.Address, key, proofs[i][j])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package oracle
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// The block tags accepted by the nodes in place of a block number.
+const (
+	LatestBlock    = "latest"
+	SafeBlock      = "safe"
+	FinalizedBlock = "finalized"
+	EarliestBlock  = "earliest"
+	PendingBlock   = "pending"
+)
+
+// BlockRef identifies a block as in EIP-1898: by number, by tag or by hash. For a hash
+// RequireCanonical makes the node fail the request if the block is not in the canonical
+// chain (anymore).
+type BlockRef struct {
+	Number           *big.Int
+	Tag              string
+	Hash             *common.Hash
+	RequireCanonical bool
+}
+
+// BlockNumberRef returns the reference to the block with the given number.
+func BlockNumberRef(number *big.Int) BlockRef {
+	return BlockRef{Number: new(big.Int).Set(number)}
+}
+
+// BlockTagRef returns the reference to the block with the given tag, e.g. FinalizedBlock.
+func BlockTagRef(tag string) BlockRef {
+	return BlockRef{Tag: tag}
+}
+
+// BlockHashRef returns the reference to the block with the given hash.
+func BlockHashRef(hash common.Hash, requireCanonical bool) BlockRef {
+	return BlockRef{Hash: &hash, RequireCanonical: requireCanonical}
+}
+
+// ParseBlockRef converts a decimal or hex block number, a block tag or a block hash
+// into BlockRef.
+func ParseBlockRef(s string) (BlockRef, error) {
+	switch s {
+	case LatestBlock, SafeBlock, FinalizedBlock, EarliestBlock, PendingBlock:
+		return BlockTagRef(s), nil
+	}
+	if strings.HasPrefix(s, "0x") && len(s) == 2+2*common.HashLength {
+		hash := common.HexToHash(s)
+		return BlockHashRef(hash, false), nil
+	}
+	if strings.HasPrefix(s, "0x") {
+		number, err := hexutil.DecodeBig(s)
+		if err != nil {
+			return BlockRef{}, fmt.Errorf("invalid block number %q: %v", s, err)
+		}
+		return BlockRef{Number: number}, nil
+	}
+	number, ok := new(big.Int).SetString(s, 10)
+	if !ok || number.Sign() < 0 {
+		return BlockRef{}, fmt.Errorf("invalid block reference %q", s)
+	}
+	return BlockRef{Number: number}, nil
+}
+
+func (r BlockRef) String() string {
+	switch {
+	case r.Hash != nil:
+		return r.Hash.Hex()
+	case r.Number != nil:
+		return r.Number.String()
+	case r.Tag != "":
+		return r.Tag
+	}
+	return LatestBlock
+}
+
+// MarshalJSON encodes the reference as the block parameter of the JSON-RPC calls: a hex
+// number, a tag or the {"blockHash", "requireCanonical"} object. An empty reference is
+// the latest block.
+func (r BlockRef) MarshalJSON() ([]byte, error) {
+	switch {
+	case r.Hash != nil:
+		return json.Marshal(struct {
+			BlockHash        common.Hash `json:"blockHash"`
+			RequireCanonical bool        `json:"requireCanonical"`
+		}{*r.Hash, r.RequireCanonical})
+	case r.Number != nil:
+		return json.Marshal(fmt.Sprintf("0x%x", r.Number))
+	case r.Tag != "":
+		return json.Marshal(r.Tag)
+	}
+	return json.Marshal(LatestBlock)
+}
+
+// UnmarshalJSON accepts a JSON number, any string accepted by ParseBlockRef and the
+// EIP-1898 objects {"blockHash": ..., "requireCanonical": ...} and {"blockNumber": ...}.
+func (r *BlockRef) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		ref, err := ParseBlockRef(s)
+		if err != nil {
+			return err
+		}
+		*r = ref
+		return nil
+	}
+	var number uint64
+	if err := json.Unmarshal(data, &number); err == nil {
+		*r = BlockRef{Number: new(big.Int).SetUint64(number)}
+		return nil
+	}
+	var obj struct {
+		BlockNumber      *hexutil.Big `json:"blockNumber"`
+		BlockHash        *common.Hash `json:"blockHash"`
+		RequireCanonical bool         `json:"requireCanonical"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return fmt.Errorf("invalid block reference %s: %v", data, err)
+	}
+	switch {
+	case obj.BlockHash != nil && obj.BlockNumber != nil:
+		return fmt.Errorf("invalid block reference %s: both blockHash and blockNumber", data)
+	case obj.BlockHash != nil:
+		*r = BlockHashRef(*obj.BlockHash, obj.RequireCanonical)
+	case obj.BlockNumber != nil:
+		*r = BlockRef{Number: (*big.Int)(obj.BlockNumber)}
+	default:
+		return fmt.Errorf("invalid block reference %s", data)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package oracle
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestBlockRefJSON(t *testing.T) {
+	hash := common.HexToHash("0x1234")
+	for _, tc := range []struct {
+		in   string
+		ref  BlockRef
+		json string
+	}{
+		{`"0x10"`, BlockNumberRef(big.NewInt(16)), `"0x10"`},
+		{`16`, BlockNumberRef(big.NewInt(16)), `"0x10"`},
+		{`"16"`, BlockNumberRef(big.NewInt(16)), `"0x10"`},
+		{`"finalized"`, BlockTagRef(FinalizedBlock), `"finalized"`},
+		{`"` + hash.Hex() + `"`, BlockHashRef(hash, false), `{"blockHash":"` + hash.Hex() + `","requireCanonical":false}`},
+		{`{"blockHash":"` + hash.Hex() + `","requireCanonical":true}`, BlockHashRef(hash, true), `{"blockHash":"` + hash.Hex() + `","requireCanonical":true}`},
+		{`{"blockNumber":"0x10"}`, BlockNumberRef(big.NewInt(16)), `"0x10"`},
+	} {
+		var ref BlockRef
+		if err := json.Unmarshal([]byte(tc.in), &ref); err != nil {
+			t.Errorf("%s: %v", tc.in, err)
+			continue
+		}
+		if ref.String() != tc.ref.String() || ref.RequireCanonical != tc.ref.RequireCanonical {
+			t.Errorf("%s: decoded %s, expected %s", tc.in, ref, tc.ref)
+		}
+		if enc, _ := json.Marshal(ref); string(enc) != tc.json {
+			t.Errorf("%s: encoded as %s, expected %s", tc.in, enc, tc.json)
+		}
+	}
+
+	for _, in := range []string{`"0xzz"`, `"safest"`, `-1`, `{}`, `{"blockNumber":"0x1","blockHash":"` + hash.Hex() + `"}`} {
+		var ref BlockRef
+		if err := json.Unmarshal([]byte(in), &ref); err == nil {
+			t.Errorf("%s: expected an error, got %s", in, ref)
+		}
+	}
+}
+
+func TestBlockPinnedByHash(t *testing.T) {
+	s := newVerifiedState()
+	header := londonHeader()
+	header.Root = s.trie.Hash()
+	hash := header.Hash()
+
+	var blockParams []json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		var req testRequest
+		json.Unmarshal(data, &req)
+		switch req.Method {
+		case "eth_getBlockByNumber", "eth_getBlockByHash":
+			blockParams = append(blockParams, req.Params[0])
+			w.Write([]byte(headerResponse(header, hash)))
+		case "eth_getProof":
+			blockParams = append(blockParams, req.Params[2])
+			json.NewEncoder(w).Encode(s.answer(req))
+		}
+	}))
+	defer server.Close()
+
+	for _, ref := range []BlockRef{BlockTagRef(SafeBlock), BlockHashRef(hash, true)} {
+		blockParams = nil
+		o := testOracle(server.URL)
+		h, err := o.PrefetchBlock(ref, true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := o.PrefetchAccount(h.Number, verifiedAddr, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		pinned, _ := json.Marshal(BlockHashRef(hash, ref.RequireCanonical))
+		if len(blockParams) != 2 || string(blockParams[1]) != string(pinned) {
+			t.Errorf("%s: the proof requested for %s, expected %s", ref, blockParams, pinned)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package oracle
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestRecordReplay(t *testing.T) {
+	addr := common.HexToAddress("0x1")
+	s := newTestState(map[common.Address][]common.Hash{addr: nil, common.HexToAddress("0x2"): nil})
+	calls := make(map[string]int)
+	server := s.server(calls)
+	dir, err := ioutil.TempDir("", "oracle-fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blockNum := big.NewInt(1)
+
+	recorder := NewRPCOracle(server.URL)
+	recorder.CacheMode, recorder.CacheDir = Record, dir
+	recorded, err := recorder.PrefetchAccount(blockNum, addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	replayer := NewRPCOracle(server.URL)
+	replayer.CacheMode, replayer.CacheDir = Replay, dir
+	replayed, err := replayer.PrefetchAccount(blockNum, addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls["eth_getProof"] != 1 || calls["eth_getBlockByNumber"] != 1 {
+		t.Fatalf("expected one eth_getProof and one eth_getBlockByNumber request to the node, got %v", calls)
+	}
+	if len(recorded) == 0 || len(replayed) != len(recorded) || recorded[0] != replayed[0] {
+		t.Fatalf("replayed proof %v differs from the recorded one %v", replayed, recorded)
+	}
+	node := common.FromHex(replayed[0])
+	if val := replayer.Preimage(crypto.Keccak256Hash(node)); !bytes.Equal(val, node) {
+		t.Fatalf("replayed proof node not in the preimages")
+	}
+}
+
+func TestReplayMiss(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oracle-fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := NewRPCOracle("http://localhost:1")
+	o.CacheMode, o.CacheDir = Replay, dir
+	o.roots[1] = emptyRoot
+
+	_, err = o.PrefetchAccount(big.NewInt(1), common.HexToAddress("0x1"), nil)
+	var missErr *FixtureMissError
+	if !errors.As(err, &missErr) {
+		t.Fatalf("expected FixtureMissError, got %v", err)
+	}
+}
+
+func TestCacheModeFromEnv(t *testing.T) {
+	os.Setenv(cacheModeEnv, "replayy")
+	defer os.Unsetenv(cacheModeEnv)
+
+	o := NewRPCOracle("http://localhost:1")
+	if o.CacheMode != Passthrough || o.EnvError == nil {
+		t.Fatalf("expected Passthrough with the error, got %v, %v", o.CacheMode, o.EnvError)
+	}
+	o.roots[1] = emptyRoot
+	if _, err := o.PrefetchAccount(big.NewInt(1), common.HexToAddress("0x1"), nil); err != o.EnvError {
+		t.Fatalf("expected the error of the cache mode, got %v", err)
+	}
+}
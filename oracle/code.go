@@ -0,0 +1,59 @@
+package oracle
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// emptyCodeHash is the code hash of the accounts without code.
+var emptyCodeHash = crypto.Keccak256Hash(nil)
+
+// PrefetchCode fetches the code with the hash codeHash of the account with the hash
+// addrHash and stores it as the preimage of codeHash. The code is looked up by its hash,
+// it is requested only if no account with the same code has been fetched before.
+//
+// eth_getCode needs the address, it has to have been seen in a proof request before,
+// MissingAddressError is returned otherwise. The code is stored only if it hashes to
+// codeHash, CodeHashError is returned otherwise.
+func (o *RPCOracle) PrefetchCode(blockNumber *big.Int, addrHash common.Hash, codeHash common.Hash) error {
+	if codeHash == emptyCodeHash {
+		return o.Store.Put(codeHash, []byte{})
+	}
+	if _, err := o.Store.Get(codeHash); err == nil {
+		return nil
+	} else if !errors.Is(err, ErrPreimageNotFound) {
+		return err
+	}
+
+	addr, ok := o.unhash(addrHash)
+	if !ok {
+		return &MissingAddressError{AddrHash: addrHash}
+	}
+	code, err := o.getCode(blockNumber, addr)
+	if err != nil {
+		return err
+	}
+	if hash := crypto.Keccak256Hash(code); hash != codeHash {
+		return &CodeHashError{Address: addr, BlockNumber: blockNumber, CodeHash: codeHash, Computed: hash}
+	}
+	return o.Store.Put(codeHash, code)
+}
+
+func (o *RPCOracle) getCode(blockNumber *big.Int, addr common.Address) ([]byte, error) {
+	r := jsonreq{Jsonrpc: "2.0", Method: "eth_getCode", Id: 1}
+	r.Params = make([]interface{}, 2)
+	r.Params[0] = addr
+	r.Params[1] = o.blockRef(blockNumber)
+
+	// curl -X POST --data '{"jsonrpc":"2.0","method":"eth_getCode","params":["0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b", "0x2"],"id":1}'
+
+	var result hexutil.Bytes
+	if err := o.call(r, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package oracle
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestPrefetchCode(t *testing.T) {
+	code := []byte{0x60, 0x01, 0x60, 0x00, 0x55}
+	codeHash := crypto.Keccak256Hash(code)
+	s := newVerifiedState()
+	s.code = map[common.Address][]byte{verifiedAddr: code}
+	calls := make(map[string]int)
+	server := s.server(calls)
+	defer server.Close()
+	o := testOracle(server.URL)
+
+	addrHash := crypto.Keccak256Hash(verifiedAddr[:])
+	var me *MissingAddressError
+	if err := o.PrefetchCode(big.NewInt(1), addrHash, codeHash); !errors.As(err, &me) || me.AddrHash != addrHash {
+		t.Fatalf("expected a MissingAddressError, got %v", err)
+	}
+	if calls["eth_getCode"] != 0 {
+		t.Fatal("code requested for an unknown address")
+	}
+
+	if _, err := o.PrefetchAccount(big.NewInt(1), verifiedAddr, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.PrefetchCode(big.NewInt(1), addrHash, codeHash); err != nil {
+		t.Fatal(err)
+	}
+	if val := o.Preimage(codeHash); !bytes.Equal(val, code) {
+		t.Fatalf("wrong code %x", val)
+	}
+	// The code is found by its hash for any account.
+	if err := o.PrefetchCode(big.NewInt(1), common.HexToHash("0x1234"), codeHash); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.PrefetchCode(big.NewInt(1), common.HexToHash("0x1234"), crypto.Keccak256Hash(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if calls["eth_getCode"] != 1 {
+		t.Fatalf("expected one eth_getCode call, got %d", calls["eth_getCode"])
+	}
+}
+
+func TestCodeHashMismatch(t *testing.T) {
+	s := newVerifiedState()
+	s.code = map[common.Address][]byte{verifiedAddr: {0x60, 0x01}}
+	server := s.server(make(map[string]int))
+	defer server.Close()
+	o := testOracle(server.URL)
+
+	if _, err := o.PrefetchAccount(big.NewInt(1), verifiedAddr, nil); err != nil {
+		t.Fatal(err)
+	}
+	codeHash := crypto.Keccak256Hash([]byte{0x60, 0x02})
+	var ce *CodeHashError
+	if err := o.PrefetchCode(big.NewInt(1), crypto.Keccak256Hash(verifiedAddr[:]), codeHash); !errors.As(err, &ce) || ce.Address != verifiedAddr {
+		t.Fatalf("expected a CodeHashError, got %v", err)
+	}
+	if _, err := o.Store.Get(codeHash); err == nil {
+		t.Fatal("code with a wrong hash stored")
+	}
+}
@@ -0,0 +1,215 @@
+package oracle
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TransportError is returned when the request couldn't be sent to the node or
+// the response couldn't be read.
+type TransportError struct {
+	Url string
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("transport error (%s): %v", e.Url, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatusError is returned when the node responds with a non-2xx HTTP status.
+type HTTPStatusError struct {
+	Url        string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("HTTP status %d (%s): %s", e.StatusCode, e.Url, e.Body)
+}
+
+// RPCError is the JSON-RPC error object returned by the node.
+type RPCError struct {
+	Method  string
+	Code    int
+	Message string
+	Data    json.RawMessage
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s: JSON-RPC error %d: %s", e.Method, e.Code, e.Message)
+}
+
+// MalformedResultError is returned when the response (or its result) can't be decoded
+// or doesn't contain what was requested.
+type MalformedResultError struct {
+	Method string
+	Err    error
+}
+
+func (e *MalformedResultError) Error() string {
+	return fmt.Sprintf("%s: malformed result: %v", e.Method, e.Err)
+}
+
+func (e *MalformedResultError) Unwrap() error {
+	return e.Err
+}
+
+// ProofError is returned when the proof returned by eth_getProof doesn't match the
+// state root of the block (or the storage root of the account for storage proofs).
+type ProofError struct {
+	Address     common.Address
+	Key         *common.Hash // nil for the account proof
+	BlockNumber *big.Int
+	Err         error
+}
+
+func (e *ProofError) Error() string {
+	if e.Key != nil {
+		return fmt.Sprintf("invalid storage proof for %s key %s at block %d: %v", e.Address, e.Key, e.BlockNumber, e.Err)
+	}
+	return fmt.Sprintf("invalid account proof for %s at block %d: %v", e.Address, e.BlockNumber, e.Err)
+}
+
+func (e *ProofError) Unwrap() error {
+	return e.Err
+}
+
+// HeaderHashError is returned when the hash of the header returned by eth_getBlockByNumber
+// differs from the block hash returned with it, e.g. when the header has fields of a fork
+// which FullHeader doesn't know.
+type HeaderHashError struct {
+	BlockNumber *big.Int
+	Hash        common.Hash // returned by the node
+	Computed    common.Hash
+}
+
+func (e *HeaderHashError) Error() string {
+	return fmt.Sprintf("hash of the header of block %d is %s, the node returned %s", e.BlockNumber, e.Computed, e.Hash)
+}
+
+// BlockNotFoundError is returned when the node doesn't have the block, e.g. the block
+// after the head of the chain.
+type BlockNotFoundError struct {
+	BlockNumber *big.Int
+}
+
+func (e *BlockNotFoundError) Error() string {
+	return fmt.Sprintf("block %d not found", e.BlockNumber)
+}
+
+// FixtureMissError is returned in the Replay cache mode when there is no fixture for
+// the request.
+type FixtureMissError struct {
+	Url     string
+	Dir     string
+	Request []byte
+	Err     error
+}
+
+func (e *FixtureMissError) Error() string {
+	return fmt.Sprintf("replay: no fixture for request %s to %s in %s: %v", e.Request, e.Url, e.Dir, e.Err)
+}
+
+func (e *FixtureMissError) Unwrap() error {
+	return e.Err
+}
+
+// FixtureError is returned when a fixture can't be read in the Replay cache mode (it is
+// corrupted) or can't be written in the Record mode.
+type FixtureError struct {
+	Path string
+	Err  error
+}
+
+func (e *FixtureError) Error() string {
+	return fmt.Sprintf("fixture %s: %v", e.Path, e.Err)
+}
+
+func (e *FixtureError) Unwrap() error {
+	return e.Err
+}
+
+// TransitionError is returned by BlockTransition.Check when the state root computed
+// after applying the transactions of the block differs from the state root of the block.
+type TransitionError struct {
+	BlockNumber *big.Int
+	Root        common.Hash
+	Expected    common.Hash
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("bad transition to block %d: state root %s != %s", e.BlockNumber, e.Root, e.Expected)
+}
+
+// MissingAddressError is returned when the code of an account is needed but its address,
+// which eth_getCode requires, is unknown: only the hashes of the addresses are in the
+// trie, the addresses are known from the proof requests.
+type MissingAddressError struct {
+	AddrHash common.Hash
+}
+
+func (e *MissingAddressError) Error() string {
+	return fmt.Sprintf("missing address preimage for %s", e.AddrHash)
+}
+
+// CodeHashError is returned when the code returned by eth_getCode doesn't hash to the
+// code hash of the account.
+type CodeHashError struct {
+	Address     common.Address
+	BlockNumber *big.Int
+	CodeHash    common.Hash
+	Computed    common.Hash
+}
+
+func (e *CodeHashError) Error() string {
+	return fmt.Sprintf("code of %s at block %d hashes to %s, the code hash is %s", e.Address, e.BlockNumber, e.Computed, e.CodeHash)
+}
+
+// DisagreementError is returned in the Quorum policy when two endpoints return different
+// proofs for the same eth_getProof request, both responses are attached.
+type DisagreementError struct {
+	Method    string
+	Endpoints [2]string
+	Responses [2][]byte
+}
+
+func (e *DisagreementError) Error() string {
+	return fmt.Sprintf("%s: %s and %s disagree:\n%s\n%s", e.Method, e.Endpoints[0], e.Endpoints[1], e.Responses[0], e.Responses[1])
+}
+
+// QuorumError is returned in the Quorum policy when fewer endpoints than required returned
+// the proofs, Err is the error of the last endpoint which failed.
+type QuorumError struct {
+	Responded int
+	Required  int
+	Err       error
+}
+
+func (e *QuorumError) Error() string {
+	return fmt.Sprintf("%d endpoints returned the proofs, %d required: %v", e.Responded, e.Required, e.Err)
+}
+
+func (e *QuorumError) Unwrap() error {
+	return e.Err
+}
+
+// retryable returns whether the request that failed with err might succeed when sent again.
+func retryable(err error) bool {
+	switch e := err.(type) {
+	case *TransportError:
+		return true
+	case *HTTPStatusError:
+		return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+	case *QuorumError:
+		return retryable(e.Err)
+	}
+	return false
+}
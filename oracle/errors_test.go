@@ -0,0 +1,221 @@
+package oracle
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// respondWith returns a server which answers the requests with the handlers in turn,
+// the last handler answers all the remaining requests.
+func respondWith(calls *int, handlers ...http.HandlerFunc) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := handlers[len(handlers)-1]
+		if *calls < len(handlers) {
+			h = handlers[*calls]
+		}
+		*calls++
+		h(w, r)
+	}))
+}
+
+func status(code int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(code), code)
+	}
+}
+
+func body(s string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(s))
+	}
+}
+
+func testOracle(url string) *RPCOracle {
+	o := NewRPCOracle(url)
+	o.CacheMode = Passthrough
+	o.RetryBackoff = time.Millisecond
+	return o
+}
+
+func TestTransportError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	o := testOracle(server.URL)
+	_, err := o.PrefetchAccount(big.NewInt(1), common.HexToAddress("0x1"), nil)
+	var te *TransportError
+	if !errors.As(err, &te) {
+		t.Fatalf("expected a TransportError, got %v", err)
+	}
+}
+
+func TestHTTPStatusErrorRetried(t *testing.T) {
+	calls := 0
+	server := respondWith(&calls, status(http.StatusInternalServerError))
+	defer server.Close()
+
+	o := testOracle(server.URL)
+	o.Retries = 2
+	_, err := o.PrefetchAccount(big.NewInt(1), common.HexToAddress("0x1"), nil)
+	var se *HTTPStatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected an HTTPStatusError with status 500, got %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected the request to be sent 3 times, got %d", calls)
+	}
+}
+
+func TestHTTPStatusErrorNotRetried(t *testing.T) {
+	calls := 0
+	server := respondWith(&calls, status(http.StatusUnauthorized))
+	defer server.Close()
+
+	o := testOracle(server.URL)
+	_, err := o.PrefetchAccount(big.NewInt(1), common.HexToAddress("0x1"), nil)
+	var se *HTTPStatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected an HTTPStatusError with status 401, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single request, got %d", calls)
+	}
+}
+
+// proofResponse is the proof of an account in an empty state.
+const proofResponse = `{"jsonrpc":"2.0","id":1,"result":{"accountProof":[],"storageHash":"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421","storageProof":[]}}`
+
+func TestRetrySucceeds(t *testing.T) {
+	calls := 0
+	server := respondWith(&calls,
+		status(http.StatusServiceUnavailable),
+		status(http.StatusTooManyRequests),
+		body(proofResponse))
+	defer server.Close()
+
+	o := testOracle(server.URL)
+	o.roots[1] = emptyRoot
+	if _, err := o.PrefetchAccount(big.NewInt(1), common.HexToAddress("0x1"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected the proof after 3 requests, got %d", calls)
+	}
+}
+
+func TestRPCError(t *testing.T) {
+	calls := 0
+	server := respondWith(&calls, body(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"missing trie node"}}`))
+	defer server.Close()
+
+	o := testOracle(server.URL)
+	o.roots[1] = emptyRoot
+	_, err := o.PrefetchStorage(big.NewInt(1), common.HexToAddress("0x1"), common.Hash{}, nil)
+	var re *RPCError
+	if !errors.As(err, &re) || re.Code != -32000 || re.Message != "missing trie node" || re.Method != "eth_getProof" {
+		t.Fatalf("expected an RPCError, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("JSON-RPC errors should not be retried, got %d requests", calls)
+	}
+
+	// A failed request is not cached, it is sent again on the next call.
+	o.PrefetchStorage(big.NewInt(1), common.HexToAddress("0x1"), common.Hash{}, nil)
+	if calls != 2 {
+		t.Fatalf("failed request was cached")
+	}
+}
+
+func TestMalformedResult(t *testing.T) {
+	for name, resp := range map[string]string{
+		"not JSON":         `<html>bad gateway</html>`,
+		"null result":      `{"jsonrpc":"2.0","id":1,"result":null}`,
+		"wrong type":       `{"jsonrpc":"2.0","id":1,"result":"0x01"}`,
+		"bad hex":          `{"jsonrpc":"2.0","id":1,"result":{"accountProof":["0xzz"]}}`,
+		"no storage proof": `{"jsonrpc":"2.0","id":1,"result":{"accountProof":[],"storageProof":[]}}`,
+	} {
+		calls := 0
+		server := respondWith(&calls, body(resp))
+		o := testOracle(server.URL)
+		o.roots[1] = emptyRoot
+		_, err := o.PrefetchStorage(big.NewInt(1), common.HexToAddress("0x1"), common.Hash{}, nil)
+		server.Close()
+
+		var me *MalformedResultError
+		if !errors.As(err, &me) {
+			t.Errorf("%s: expected a MalformedResultError, got %v", name, err)
+		}
+	}
+}
+
+func TestPrefetchBlockError(t *testing.T) {
+	calls := 0
+	server := respondWith(&calls, body(`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"invalid argument"}}`))
+	defer server.Close()
+
+	o := testOracle(server.URL)
+	_, err := o.PrefetchBlock(BlockNumberRef(big.NewInt(1)), true, nil)
+	var re *RPCError
+	if !errors.As(err, &re) || re.Method != "eth_getBlockByNumber" {
+		t.Fatalf("expected an RPCError, got %v", err)
+	}
+}
+
+func TestFixtureError(t *testing.T) {
+	calls := 0
+	server := respondWith(&calls, body(proofResponse))
+	defer server.Close()
+	file, err := ioutil.TempFile("", "oracle-fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	// The fixture directory can't be created, the response isn't returned without its fixture.
+	o := testOracle(server.URL)
+	o.CacheMode, o.CacheDir = Record, file.Name()
+	o.roots[1] = emptyRoot
+	_, err = o.PrefetchAccount(big.NewInt(1), common.HexToAddress("0x1"), nil)
+	var fe *FixtureError
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected a FixtureError, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("fixture errors should not be retried, got %d requests", calls)
+	}
+
+	// A corrupted fixture.
+	dir, err := ioutil.TempDir("", "oracle-fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	o.CacheMode, o.CacheDir = Record, dir
+	if _, err := o.PrefetchAccount(big.NewInt(1), common.HexToAddress("0x1"), nil); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one fixture, got %v (%v)", files, err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, files[0].Name()), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	replayer := testOracle(server.URL)
+	replayer.CacheMode, replayer.CacheDir = Replay, dir
+	replayer.roots[1] = emptyRoot
+	_, err = replayer.PrefetchAccount(big.NewInt(1), common.HexToAddress("0x1"), nil)
+	if !errors.As(err, &fe) || fe.Path != filepath.Join(dir, files[0].Name()) {
+		t.Fatalf("expected a FixtureError, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package oracle
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DefaultNodeMethod is the JSON-RPC method used for fetching the missing trie nodes,
+// geth answers debug_dbGet with the raw database value which for a trie node (in the
+// hash-based scheme) is stored under its hash.
+const DefaultNodeMethod = "debug_dbGet"
+
+// NodeFetcher returns the preimage of hash, it is used for the trie nodes which
+// weren't part of any fetched proof.
+type NodeFetcher func(hash common.Hash) ([]byte, error)
+
+// fetchNode obtains the preimage which isn't in the store with FetchNode (or NodeMethod),
+// checks its hash and stores it.
+func (o *RPCOracle) fetchNode(hash common.Hash) ([]byte, error) {
+	fetch := o.FetchNode
+	if fetch == nil {
+		if o.NodeMethod == "" {
+			return nil, errors.New("node fetching disabled")
+		}
+		fetch = o.getNode
+	}
+	val, err := fetch(hash)
+	if err != nil {
+		return nil, err
+	}
+	if comphash := crypto.Keccak256Hash(val); comphash != hash {
+		return nil, fmt.Errorf("fetched node hashes to %s", comphash)
+	}
+	if err := o.Store.Put(hash, val); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// getNode requests the preimage of hash with NodeMethod.
+func (o *RPCOracle) getNode(hash common.Hash) ([]byte, error) {
+	r := jsonreq{Jsonrpc: "2.0", Method: o.NodeMethod, Id: 1}
+	r.Params = make([]interface{}, 1)
+	r.Params[0] = hash.Hex()
+
+	var result hexutil.Bytes
+	if err := o.call(r, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,68 @@
+package oracle
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestFetchMissingNode(t *testing.T) {
+	s := newVerifiedState()
+	calls := make(map[string]int)
+	server := s.server(calls)
+	defer server.Close()
+	o := testOracle(server.URL)
+
+	if _, err := o.PrefetchAccount(big.NewInt(1), common.HexToAddress("0x2"), nil); err != nil {
+		t.Fatal(err)
+	}
+	// The storage root of another account is not part of the proof.
+	root := s.storage[verifiedAddr].Hash()
+	for i := 0; i < 2; i++ {
+		if o.Preimage(root) == nil {
+			t.Fatal("node not fetched")
+		}
+	}
+	if calls[DefaultNodeMethod] != 1 {
+		t.Fatalf("expected one %s call, got %d", DefaultNodeMethod, calls[DefaultNodeMethod])
+	}
+	if o.Preimage(common.HexToHash("0x1")) != nil {
+		t.Fatal("unknown node found")
+	}
+}
+
+func TestNodeFetcher(t *testing.T) {
+	node := []byte{1, 2, 3}
+	hash := crypto.Keccak256Hash(node)
+
+	o := testOracle("http://localhost:0")
+	o.FetchNode = func(h common.Hash) ([]byte, error) {
+		if h == hash {
+			return node, nil
+		}
+		if h == common.HexToHash("0x1") {
+			// A node which doesn't match the hash is not accepted.
+			return node, nil
+		}
+		return nil, errors.New("not found")
+	}
+	if val := o.Preimage(hash); !bytes.Equal(val, node) {
+		t.Fatalf("wrong node %x", val)
+	}
+	if val, err := o.Store.Get(hash); err != nil || !bytes.Equal(val, node) {
+		t.Fatal("fetched node not stored")
+	}
+	if o.Preimage(common.HexToHash("0x1")) != nil {
+		t.Fatal("node with a wrong hash accepted")
+	}
+	if _, err := o.Store.Get(common.HexToHash("0x1")); err == nil {
+		t.Fatal("node with a wrong hash stored")
+	}
+	if o.Preimage(common.HexToHash("0x2")) != nil {
+		t.Fatal("unknown node found")
+	}
+}
@@ -0,0 +1,78 @@
+package oracle
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// FullHeader is the block header with the fields of all forks, types.Header of the
+// go-ethereum version in use ends with BaseFee (London). The fields added by a fork are
+// optional in the RLP encoding, so the header of a block before the fork encodes (and
+// hashes) exactly as the block did.
+type FullHeader struct {
+	ParentHash  common.Hash
+	UncleHash   common.Hash
+	Coinbase    common.Address
+	Root        common.Hash
+	TxHash      common.Hash
+	ReceiptHash common.Hash
+	Bloom       types.Bloom
+	Difficulty  *big.Int
+	Number      *big.Int
+	GasLimit    uint64
+	GasUsed     uint64
+	Time        uint64
+	Extra       []byte
+	MixDigest   common.Hash
+	Nonce       types.BlockNonce
+
+	// London (EIP-1559)
+	BaseFee *big.Int `rlp:"optional"`
+	// Shanghai (EIP-4895)
+	WithdrawalsRoot *common.Hash `rlp:"optional"`
+	// Cancun (EIP-4844, EIP-4788)
+	BlobGasUsed      *uint64      `rlp:"optional"`
+	ExcessBlobGas    *uint64      `rlp:"optional"`
+	ParentBeaconRoot *common.Hash `rlp:"optional"`
+	// Prague (EIP-7685)
+	RequestsHash *common.Hash `rlp:"optional"`
+}
+
+// Hash returns the block hash, the keccak256 hash of the RLP encoding of the header.
+func (h *FullHeader) Hash() common.Hash {
+	return crypto.Keccak256Hash(h.RLP())
+}
+
+// RLP returns the RLP encoding of the header.
+func (h *FullHeader) RLP() []byte {
+	// Encoding the header cannot fail, ok to ignore the error.
+	enc, _ := rlp.EncodeToBytes(h)
+	return enc
+}
+
+// Header returns the header as types.Header, e.g. for the EVM. The fields added after
+// London are dropped, its hash thus differs from the block hash for the later blocks.
+func (h *FullHeader) Header() *types.Header {
+	return &types.Header{
+		ParentHash:  h.ParentHash,
+		UncleHash:   h.UncleHash,
+		Coinbase:    h.Coinbase,
+		Root:        h.Root,
+		TxHash:      h.TxHash,
+		ReceiptHash: h.ReceiptHash,
+		Bloom:       h.Bloom,
+		Difficulty:  h.Difficulty,
+		Number:      h.Number,
+		GasLimit:    h.GasLimit,
+		GasUsed:     h.GasUsed,
+		Time:        h.Time,
+		Extra:       h.Extra,
+		MixDigest:   h.MixDigest,
+		Nonce:       h.Nonce,
+		BaseFee:     h.BaseFee,
+	}
+}
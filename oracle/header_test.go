@@ -0,0 +1,164 @@
+package oracle
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func londonHeader() FullHeader {
+	return FullHeader{
+		ParentHash:  common.HexToHash("0x01"),
+		UncleHash:   types.EmptyUncleHash,
+		Coinbase:    common.HexToAddress("0x02"),
+		Root:        common.HexToHash("0x03"),
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: types.EmptyRootHash,
+		Difficulty:  big.NewInt(0),
+		Number:      big.NewInt(1),
+		GasLimit:    30000000,
+		Time:        1700000000,
+		Extra:       []byte{},
+		BaseFee:     big.NewInt(7),
+	}
+}
+
+func cancunHeader() FullHeader {
+	h := londonHeader()
+	zero, excess := uint64(0), uint64(0x40000)
+	h.WithdrawalsRoot = &types.EmptyRootHash
+	h.BlobGasUsed = &zero
+	h.ExcessBlobGas = &excess
+	h.ParentBeaconRoot = &common.Hash{4}
+	return h
+}
+
+// headerResponse returns the eth_getBlockByNumber response with the header h and hash.
+func headerResponse(h FullHeader, hash common.Hash) string {
+	return blockResponse(h, hash, []interface{}{})
+}
+
+// blockResponse is headerResponse for a block with the transactions txs.
+func blockResponse(h FullHeader, hash common.Hash, txs []interface{}) string {
+	res := map[string]interface{}{
+		"hash":             hash,
+		"parentHash":       h.ParentHash,
+		"sha3Uncles":       h.UncleHash,
+		"miner":            h.Coinbase,
+		"stateRoot":        h.Root,
+		"transactionsRoot": h.TxHash,
+		"receiptsRoot":     h.ReceiptHash,
+		"logsBloom":        h.Bloom,
+		"difficulty":       (*hexutil.Big)(h.Difficulty),
+		"number":           (*hexutil.Big)(h.Number),
+		"gasLimit":         hexutil.Uint64(h.GasLimit),
+		"gasUsed":          hexutil.Uint64(h.GasUsed),
+		"timestamp":        hexutil.Uint64(h.Time),
+		"extraData":        hexutil.Bytes(h.Extra),
+		"mixHash":          h.MixDigest,
+		"nonce":            h.Nonce,
+		"baseFeePerGas":    (*hexutil.Big)(h.BaseFee),
+		"transactions":     txs,
+	}
+	if h.WithdrawalsRoot != nil {
+		res["withdrawalsRoot"] = h.WithdrawalsRoot
+	}
+	if h.BlobGasUsed != nil {
+		res["blobGasUsed"] = hexutil.Uint64(*h.BlobGasUsed)
+		res["excessBlobGas"] = hexutil.Uint64(*h.ExcessBlobGas)
+		res["parentBeaconBlockRoot"] = h.ParentBeaconRoot
+	}
+	if h.RequestsHash != nil {
+		res["requestsHash"] = h.RequestsHash
+	}
+	result, _ := json.Marshal(res)
+	resp, _ := json.Marshal(jsonresp{Jsonrpc: "2.0", Id: 1, Result: result})
+	return string(resp)
+}
+
+func TestLondonHeaderHash(t *testing.T) {
+	h := londonHeader()
+	legacy := types.Header{
+		ParentHash:  h.ParentHash,
+		UncleHash:   h.UncleHash,
+		Coinbase:    h.Coinbase,
+		Root:        h.Root,
+		TxHash:      h.TxHash,
+		ReceiptHash: h.ReceiptHash,
+		Difficulty:  h.Difficulty,
+		Number:      h.Number,
+		GasLimit:    h.GasLimit,
+		Time:        h.Time,
+		Extra:       h.Extra,
+		BaseFee:     h.BaseFee,
+	}
+	if h.Hash() != legacy.Hash() {
+		t.Fatalf("hash %s of the London header differs from %s", h.Hash(), legacy.Hash())
+	}
+}
+
+func TestHeaderFieldsOfAllForks(t *testing.T) {
+	prague := cancunHeader()
+	prague.RequestsHash = &common.Hash{5}
+	for name, tc := range map[string]struct {
+		header FullHeader
+		fields int
+	}{
+		"london": {londonHeader(), 16},
+		"cancun": {cancunHeader(), 20},
+		"prague": {prague, 21},
+	} {
+		_, content, _, err := rlp.Split(tc.header.RLP())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, _ := rlp.CountValues(content); n != tc.fields {
+			t.Errorf("%s: expected %d fields in the RLP, got %d", name, tc.fields, n)
+		}
+
+		var dec FullHeader
+		if err := rlp.DecodeBytes(tc.header.RLP(), &dec); err != nil {
+			t.Fatal(err)
+		}
+		if dec.Hash() != tc.header.Hash() {
+			t.Errorf("%s: decoded header hashes to %s, expected %s", name, dec.Hash(), tc.header.Hash())
+		}
+
+		// PrefetchBlock stores the RLP of the full header under the block hash.
+		calls := 0
+		server := respondWith(&calls, body(headerResponse(tc.header, tc.header.Hash())))
+		o := testOracle(server.URL)
+		header, err := o.PrefetchBlock(BlockNumberRef(big.NewInt(1)), true, nil)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		server.Close()
+		// The returned header has the fields of the fork, it hashes to the block hash.
+		if header.Hash() != tc.header.Hash() {
+			t.Errorf("%s: returned header hashes to %s, expected %s", name, header.Hash(), tc.header.Hash())
+		}
+		if o.Preimage(tc.header.Hash()) == nil {
+			t.Errorf("%s: header not stored under the block hash", name)
+		}
+	}
+}
+
+func TestHeaderHashMismatch(t *testing.T) {
+	h := cancunHeader()
+	calls := 0
+	server := respondWith(&calls, body(headerResponse(h, common.HexToHash("0x1234"))))
+	defer server.Close()
+
+	o := testOracle(server.URL)
+	_, err := o.PrefetchBlock(BlockNumberRef(big.NewInt(1)), true, nil)
+	var he *HeaderHashError
+	if !errors.As(err, &he) || he.Computed != h.Hash() || he.Hash != common.HexToHash("0x1234") {
+		t.Fatalf("expected a HeaderHashError, got %v", err)
+	}
+}
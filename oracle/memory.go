@@ -0,0 +1,70 @@
+package oracle
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// MemoryOracle is an Oracle without a node: all the trie nodes and code are expected to be
+// in its Store already (see state.NewMemoryDatabase), so the Prefetch methods don't fetch
+// anything.
+type MemoryOracle struct {
+	Store PreimageStore
+
+	// For generating special tests for MPT circuit:
+	PreventHashing bool
+}
+
+// NewMemoryOracle returns a MemoryOracle with an empty in-memory store.
+func NewMemoryOracle() *MemoryOracle {
+	return &MemoryOracle{Store: NewMemoryPreimageStore()}
+}
+
+func (o *MemoryOracle) PrefetchAccount(blockNumber *big.Int, addr common.Address, postProcess func(map[common.Hash][]byte)) ([]string, error) {
+	return nil, nil
+}
+
+func (o *MemoryOracle) PrefetchStorage(blockNumber *big.Int, addr common.Address, skey common.Hash, postProcess func(map[common.Hash][]byte)) ([]string, error) {
+	return nil, nil
+}
+
+func (o *MemoryOracle) PrefetchStorageKeys(blockNumber *big.Int, addr common.Address, keys []common.Hash) ([][]string, error) {
+	return nil, nil
+}
+
+func (o *MemoryOracle) Prefetch(blockNumber *big.Int, accounts []StorageKeys) error {
+	return nil
+}
+
+// PrefetchCode returns an error if the code is not in the store.
+func (o *MemoryOracle) PrefetchCode(blockNumber *big.Int, addrHash common.Hash, codeHash common.Hash) error {
+	if codeHash == emptyCodeHash {
+		return nil
+	}
+	if _, err := o.Store.Get(codeHash); err != nil {
+		return fmt.Errorf("code %s of account %s: %w", codeHash, addrHash, err)
+	}
+	return nil
+}
+
+// PrefetchBlock always fails, there are no blocks without a node.
+func (o *MemoryOracle) PrefetchBlock(block BlockRef, startBlock bool, hasher types.TrieHasher) (FullHeader, error) {
+	return FullHeader{}, errors.New("no blocks in a memory oracle")
+}
+
+func (o *MemoryOracle) Preimage(hash common.Hash) []byte {
+	val, _ := o.Store.Get(hash)
+	return val
+}
+
+func (o *MemoryOracle) PutPreimage(hash common.Hash, preimage []byte) error {
+	return o.Store.Put(hash, preimage)
+}
+
+func (o *MemoryOracle) PreventHashingInSecureTrie() bool {
+	return o.PreventHashing
+}
@@ -0,0 +1,221 @@
+package oracle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// EndpointPolicy is how a MultiTransport uses its endpoints.
+type EndpointPolicy int
+
+const (
+	// Failover sends each request to the first endpoint and, when it fails, to the next ones
+	// in turn until one of them succeeds.
+	Failover EndpointPolicy = iota
+	// Quorum sends eth_getProof requests to several endpoints and accepts the proofs only if
+	// they are identical, the other requests fail over.
+	Quorum
+)
+
+// MultiTransport sends the requests to several endpoints with an EndpointPolicy. A request
+// fails on an endpoint when the transport fails or when a response is a JSON-RPC error or
+// a null result, e.g. when the node is behind or has pruned the state of the block.
+type MultiTransport struct {
+	Urls       []string
+	Transports []Transport
+	Policy     EndpointPolicy
+
+	// QuorumSize is the number of endpoints which must return identical proofs in the Quorum
+	// policy, all endpoints when zero.
+	QuorumSize int
+}
+
+// NewMultiTransport returns a MultiTransport with the transports created by NewTransport
+// for the node URLs.
+func NewMultiTransport(nodeUrls []string, policy EndpointPolicy) (*MultiTransport, error) {
+	if len(nodeUrls) == 0 {
+		return nil, errors.New("no endpoints")
+	}
+	t := &MultiTransport{Urls: nodeUrls, Policy: policy}
+	for _, nodeUrl := range nodeUrls {
+		tr, err := NewTransport(nodeUrl)
+		if err != nil {
+			return nil, err
+		}
+		t.Transports = append(t.Transports, tr)
+	}
+	return t, nil
+}
+
+func (t *MultiTransport) Send(jsonData []byte) ([]byte, error) {
+	if t.Policy == Quorum {
+		if ids := proofRequestIds(jsonData); len(ids) > 0 {
+			return t.sendQuorum(jsonData, ids)
+		}
+	}
+	return t.sendFailover(jsonData)
+}
+
+// sendFailover returns the first successful response. When all endpoints fail, the error
+// of the last one is returned, or its response if it was a JSON-RPC error.
+func (t *MultiTransport) sendFailover(jsonData []byte) ([]byte, error) {
+	var ret []byte
+	var err error
+	for _, tr := range t.Transports {
+		ret, err = tr.Send(jsonData)
+		if err == nil && !failedResponse(ret) {
+			return ret, nil
+		}
+	}
+	return ret, err
+}
+
+// sendQuorum sends the request to the endpoints in turn until QuorumSize of them
+// respond, the proofs in all the responses must be identical.
+func (t *MultiTransport) sendQuorum(jsonData []byte, ids map[uint64]bool) ([]byte, error) {
+	size := t.QuorumSize
+	if size <= 0 || size > len(t.Transports) {
+		size = len(t.Transports)
+	}
+	var first, failed []byte
+	var firstUrl string
+	var err error
+	agreed := 0
+	for i, tr := range t.Transports {
+		ret, serr := tr.Send(jsonData)
+		if serr != nil {
+			err = serr
+			continue
+		}
+		if failedResponse(ret) {
+			failed = ret
+			continue
+		}
+		if first == nil {
+			first, firstUrl = ret, t.Urls[i]
+		} else if !sameProofs(first, ret, ids) {
+			return nil, &DisagreementError{
+				Method:    "eth_getProof",
+				Endpoints: [2]string{firstUrl, t.Urls[i]},
+				Responses: [2][]byte{first, ret},
+			}
+		}
+		if agreed++; agreed == size {
+			return first, nil
+		}
+	}
+	if agreed == 0 && failed != nil {
+		// The JSON-RPC error is decoded by the caller.
+		return failed, nil
+	}
+	if err == nil {
+		err = errors.New("JSON-RPC error responses")
+	}
+	return nil, &QuorumError{Responded: agreed, Required: size, Err: err}
+}
+
+func (t *MultiTransport) Close() error {
+	var err error
+	for _, tr := range t.Transports {
+		if cerr := tr.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// decodeResponses decodes a single or batch response.
+func decodeResponses(ret []byte) ([]jsonresp, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(ret), []byte("[")) {
+		var jrs []jsonresp
+		err := json.Unmarshal(ret, &jrs)
+		return jrs, err
+	}
+	var jr jsonresp
+	err := json.Unmarshal(ret, &jr)
+	return []jsonresp{jr}, err
+}
+
+// failedResponse returns whether the response (or any response in a batch) is a JSON-RPC
+// error or a null result.
+func failedResponse(ret []byte) bool {
+	jrs, err := decodeResponses(ret)
+	if err != nil {
+		return true
+	}
+	for _, jr := range jrs {
+		if jr.Error != nil || len(jr.Result) == 0 || bytes.Equal(jr.Result, []byte("null")) {
+			return true
+		}
+	}
+	return false
+}
+
+// proofRequestIds returns the ids of the eth_getProof requests in the single or batch request.
+func proofRequestIds(jsonData []byte) map[uint64]bool {
+	type request struct {
+		Id     uint64 `json:"id"`
+		Method string `json:"method"`
+	}
+	var reqs []request
+	if bytes.HasPrefix(bytes.TrimSpace(jsonData), []byte("[")) {
+		json.Unmarshal(jsonData, &reqs)
+	} else {
+		var r request
+		if json.Unmarshal(jsonData, &r) == nil {
+			reqs = append(reqs, r)
+		}
+	}
+	ids := make(map[uint64]bool)
+	for _, r := range reqs {
+		if r.Method == "eth_getProof" {
+			ids[r.Id] = true
+		}
+	}
+	return ids
+}
+
+// sameProofs returns whether the account and storage proofs of the eth_getProof responses
+// with the given ids are identical in both responses.
+func sameProofs(a, b []byte, ids map[uint64]bool) bool {
+	proofsA, errA := proofsById(a, ids)
+	proofsB, errB := proofsById(b, ids)
+	if errA != nil || errB != nil || len(proofsA) != len(proofsB) {
+		return false
+	}
+	for id, pa := range proofsA {
+		pb, ok := proofsB[id]
+		if !ok || pa != pb {
+			return false
+		}
+	}
+	return true
+}
+
+// proofsById returns the proofs in the responses with the given ids, each as a single
+// string of the storage hash and the proof nodes.
+func proofsById(ret []byte, ids map[uint64]bool) (map[uint64]string, error) {
+	jrs, err := decodeResponses(ret)
+	if err != nil {
+		return nil, err
+	}
+	proofs := make(map[uint64]string)
+	for _, jr := range jrs {
+		if !ids[jr.Id] {
+			continue
+		}
+		var res AccountResult
+		if err := json.Unmarshal(jr.Result, &res); err != nil {
+			return nil, err
+		}
+		parts := append([]string{res.StorageHash.Hex()}, res.AccountProof...)
+		for _, sp := range res.StorageProof {
+			parts = append(parts, "")
+			parts = append(parts, sp.Proof...)
+		}
+		proofs[jr.Id] = strings.ToLower(strings.Join(parts, ","))
+	}
+	return proofs, nil
+}
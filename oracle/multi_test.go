@@ -0,0 +1,122 @@
+package oracle
+
+import (
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func testMultiOracle(t *testing.T, policy EndpointPolicy, nodeUrls ...string) *RPCOracle {
+	o, err := NewMultiRPCOracle(nodeUrls, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.CacheMode = Passthrough
+	o.RetryBackoff = time.Millisecond
+	return o
+}
+
+const prunedResponse = `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"missing trie node"}}`
+
+func TestFailover(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	prunedCalls := 0
+	pruned := respondWith(&prunedCalls, body(prunedResponse))
+	defer pruned.Close()
+	s := newVerifiedState()
+	calls := make(map[string]int)
+	server := s.server(calls)
+	defer server.Close()
+
+	o := testMultiOracle(t, Failover, down.URL, pruned.URL, server.URL)
+	if _, err := o.PrefetchStorage(big.NewInt(1), verifiedAddr, verifiedKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	if prunedCalls != 2 || calls["eth_getBlockByNumber"] != 1 || calls["eth_getProof"] != 1 {
+		t.Fatalf("unexpected requests: %d to the pruned node, %v to the last one", prunedCalls, calls)
+	}
+
+	// When all endpoints fail, the error of the last one is returned.
+	o = testMultiOracle(t, Failover, down.URL, pruned.URL)
+	_, err := o.PrefetchStorage(big.NewInt(1), verifiedAddr, verifiedKey, nil)
+	var re *RPCError
+	if !errors.As(err, &re) || re.Message != "missing trie node" {
+		t.Fatalf("expected an RPCError, got %v", err)
+	}
+}
+
+func TestQuorum(t *testing.T) {
+	var urls []string
+	var calls []map[string]int
+	for i := 0; i < 3; i++ {
+		calls = append(calls, make(map[string]int))
+		server := newVerifiedState().server(calls[i])
+		defer server.Close()
+		urls = append(urls, server.URL)
+	}
+
+	o := testMultiOracle(t, Quorum, urls...)
+	if _, err := o.PrefetchStorage(big.NewInt(1), verifiedAddr, verifiedKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := range calls {
+		if calls[i]["eth_getProof"] != 1 {
+			t.Errorf("endpoint %d: expected one eth_getProof request, got %d", i, calls[i]["eth_getProof"])
+		}
+	}
+	// The other requests fail over, only the first endpoint is used.
+	if calls[0]["eth_getBlockByNumber"] != 1 || calls[1]["eth_getBlockByNumber"] != 0 {
+		t.Errorf("eth_getBlockByNumber was sent to several endpoints")
+	}
+}
+
+func TestQuorumSize(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	server := newVerifiedState().server(make(map[string]int))
+	defer server.Close()
+	other := newVerifiedState().server(make(map[string]int))
+	defer other.Close()
+
+	o := testMultiOracle(t, Quorum, down.URL, server.URL, other.URL)
+	o.Transport.(*MultiTransport).QuorumSize = 2
+	if _, err := o.PrefetchStorage(big.NewInt(1), verifiedAddr, verifiedKey, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	o = testMultiOracle(t, Quorum, down.URL, server.URL)
+	o.Retries = 0
+	_, err := o.PrefetchStorage(big.NewInt(1), verifiedAddr, verifiedKey, nil)
+	var qe *QuorumError
+	var te *TransportError
+	if !errors.As(err, &qe) || qe.Responded != 1 || qe.Required != 2 || !errors.As(err, &te) {
+		t.Fatalf("expected a QuorumError, got %v", err)
+	}
+}
+
+func TestQuorumDisagreement(t *testing.T) {
+	server := newVerifiedState().server(make(map[string]int))
+	defer server.Close()
+	s := newVerifiedState()
+	s.tamper = func(res *AccountResult) {
+		res.AccountProof = prove(newTestState(nil).trie, crypto.Keccak256(res.Address[:]))
+	}
+	tampered := s.server(make(map[string]int))
+	defer tampered.Close()
+
+	o := testMultiOracle(t, Quorum, server.URL, tampered.URL)
+	_, err := o.PrefetchStorage(big.NewInt(1), verifiedAddr, verifiedKey, nil)
+	var de *DisagreementError
+	if !errors.As(err, &de) {
+		t.Fatalf("expected a DisagreementError, got %v", err)
+	}
+	if de.Endpoints != [2]string{server.URL, tampered.URL} || len(de.Responses[0]) == 0 || len(de.Responses[1]) == 0 {
+		t.Fatalf("the endpoints and their responses are not attached: %+v", de)
+	}
+}
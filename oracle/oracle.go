@@ -0,0 +1,47 @@
+package oracle
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Oracle provides the trie nodes, contract code and block headers needed when
+// generating a witness. Each generator instance owns its own Oracle so that
+// witnesses for different chains or blocks can be generated in the same process.
+//
+// The Prefetch methods return the errors of the underlying source (see errors.go for
+// the errors returned by RPCOracle) instead of terminating the process.
+type Oracle interface {
+	// PrefetchAccount fetches the account proof for addr and stores its nodes as preimages.
+	PrefetchAccount(blockNumber *big.Int, addr common.Address, postProcess func(map[common.Hash][]byte)) ([]string, error)
+
+	// PrefetchStorage fetches the storage proof for skey and stores its nodes as preimages.
+	PrefetchStorage(blockNumber *big.Int, addr common.Address, skey common.Hash, postProcess func(map[common.Hash][]byte)) ([]string, error)
+
+	// PrefetchStorageKeys fetches the storage proofs for all keys of addr at once.
+	PrefetchStorageKeys(blockNumber *big.Int, addr common.Address, keys []common.Hash) ([][]string, error)
+
+	// Prefetch fetches the proofs of all accounts and storage keys at once (concurrently),
+	// so that the later Prefetch calls for them don't need the node.
+	Prefetch(blockNumber *big.Int, accounts []StorageKeys) error
+
+	// PrefetchCode fetches the code with the given hash of the account with the given
+	// address hash.
+	PrefetchCode(blockNumber *big.Int, addrHash common.Hash, codeHash common.Hash) error
+
+	// PrefetchBlock fetches the block header (and transactions for the second block).
+	// The later requests for the number of the block refer to this block.
+	PrefetchBlock(block BlockRef, startBlock bool, hasher types.TrieHasher) (FullHeader, error)
+
+	// Preimage returns the preimage of hash.
+	Preimage(hash common.Hash) []byte
+
+	// PutPreimage stores the preimage of hash.
+	PutPreimage(hash common.Hash, preimage []byte) error
+
+	// PreventHashingInSecureTrie is used for generating special tests for MPT circuit
+	// where the keys are stored in the trie without being hashed.
+	PreventHashingInSecureTrie() bool
+}
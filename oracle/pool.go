@@ -0,0 +1,126 @@
+package oracle
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultWorkers is the number of concurrent requests sent by Prefetch.
+const DefaultWorkers = 8
+
+// prefetchKeys is the maximum number of storage keys requested by a single eth_getProof
+// call in Prefetch.
+const prefetchKeys = 32
+
+// Prefetch fetches the proofs of all accounts and storage keys with Workers concurrent
+// requests. The nodes are stored, so that the later Prefetch calls for these accounts
+// and keys don't contact the node (PrefetchAccount still returns the account proof once).
+// The first error stops the remaining requests.
+func (o *RPCOracle) Prefetch(blockNumber *big.Int, accounts []StorageKeys) error {
+	// The state root is needed for verifying every proof, fetch it only once.
+	if _, err := o.stateRoot(blockNumber); err != nil {
+		return err
+	}
+
+	var jobs []StorageKeys
+	for _, acc := range accounts {
+		if len(acc.Keys) == 0 {
+			jobs = append(jobs, acc)
+		}
+		for i := 0; i < len(acc.Keys); i += prefetchKeys {
+			end := i + prefetchKeys
+			if end > len(acc.Keys) {
+				end = len(acc.Keys)
+			}
+			jobs = append(jobs, StorageKeys{Address: acc.Address, Keys: acc.Keys[i:end]})
+		}
+	}
+
+	workers := o.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	jobCh := make(chan StorageKeys)
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	done := make(chan struct{})
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobCh {
+				var err error
+				if len(job.Keys) == 0 {
+					err = o.prefetchAccountProof(blockNumber, job.Address)
+				} else {
+					_, err = o.PrefetchStorageBatch(blockNumber, []StorageKeys{job})
+				}
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						close(done)
+					})
+				}
+			}
+		}()
+	}
+
+loop:
+	for _, job := range jobs {
+		select {
+		case jobCh <- job:
+		case <-done:
+			break loop
+		}
+	}
+	close(jobCh)
+	wg.Wait()
+	return firstErr
+}
+
+// prefetchAccountProof fetches the account proof and keeps it for PrefetchAccount.
+func (o *RPCOracle) prefetchAccountProof(blockNumber *big.Int, addr common.Address) error {
+	key := fmt.Sprintf("proof_%d_%s", blockNumber, addr)
+	if o.isCached(key) {
+		return nil
+	}
+	ap, err := o.getProofAccount(blockNumber, addr, common.Hash{}, false)
+	if err != nil {
+		return err
+	}
+	newPreimages, err := proofPreimages("eth_getProof", ap)
+	if err != nil {
+		return err
+	}
+	if err := o.putPreimages(newPreimages); err != nil {
+		return err
+	}
+	o.setAccountProof(key, ap)
+	return nil
+}
+
+// setAccountProof keeps the account proof which has been fetched (and its nodes stored)
+// before PrefetchAccount was called for it.
+func (o *RPCOracle) setAccountProof(key string, proof []string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if !o.cached[key] {
+		o.accountProofs[key] = proof
+	}
+}
+
+// takeAccountProof returns the kept account proof and marks it as cached.
+func (o *RPCOracle) takeAccountProof(key string) ([]string, bool) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	proof, ok := o.accountProofs[key]
+	if ok {
+		delete(o.accountProofs, key)
+		o.cached[key] = true
+	}
+	return proof, ok
+}
@@ -0,0 +1,84 @@
+package oracle
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestPrefetch(t *testing.T) {
+	accounts := make(map[common.Address][]common.Hash)
+	var request []StorageKeys
+	for i := 1; i <= 10; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i)))
+		var keys []common.Hash
+		for k := 1; k <= 40; k++ {
+			keys = append(keys, common.BigToHash(big.NewInt(int64(k))))
+		}
+		accounts[addr] = keys
+		request = append(request, StorageKeys{Address: addr, Keys: keys})
+	}
+	// An account without keys and an account which doesn't exist.
+	accounts[common.HexToAddress("0xaa")] = nil
+	request = append(request, StorageKeys{Address: common.HexToAddress("0xaa")}, StorageKeys{Address: common.HexToAddress("0xbb")})
+
+	s := newTestState(accounts)
+	calls := make(map[string]int)
+	server := s.server(calls)
+	defer server.Close()
+	o := testOracle(server.URL)
+	o.Workers = 4
+
+	if err := o.Prefetch(big.NewInt(1), request); err != nil {
+		t.Fatal(err)
+	}
+	// 40 keys are requested in 2 calls for each of the 10 accounts, 1 call for each
+	// of the other 2 accounts.
+	if calls["eth_getProof"] != 22 {
+		t.Fatalf("expected 22 eth_getProof calls, got %d", calls["eth_getProof"])
+	}
+
+	for _, acc := range request {
+		for _, key := range acc.Keys {
+			if _, err := o.PrefetchStorage(big.NewInt(1), acc.Address, key, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// The first PrefetchAccount returns the proof fetched by Prefetch.
+		if ap, err := o.PrefetchAccount(big.NewInt(1), acc.Address, nil); err != nil || len(ap) == 0 {
+			t.Fatalf("no account proof for %s: %v", acc.Address, err)
+		}
+		if ap, _ := o.PrefetchAccount(big.NewInt(1), acc.Address, nil); ap != nil {
+			t.Fatalf("account proof for %s returned twice", acc.Address)
+		}
+	}
+	if calls["eth_getProof"] != 22 {
+		t.Fatalf("the prefetched proofs were requested again, %d eth_getProof calls", calls["eth_getProof"])
+	}
+	if o.Preimage(s.storage[common.HexToAddress("0x1")].Hash()) == nil {
+		t.Fatal("storage root not stored")
+	}
+}
+
+func TestPrefetchError(t *testing.T) {
+	s := newVerifiedState()
+	s.tamper = func(res *AccountResult) {
+		if res.Address == common.HexToAddress("0x2") {
+			res.StorageHash = common.HexToHash("0x1234")
+		}
+	}
+	server := s.server(make(map[string]int))
+	defer server.Close()
+	o := testOracle(server.URL)
+
+	err := o.Prefetch(big.NewInt(1), []StorageKeys{
+		{Address: verifiedAddr, Keys: []common.Hash{verifiedKey}},
+		{Address: common.HexToAddress("0x2"), Keys: []common.Hash{common.HexToHash("0x1")}},
+	})
+	var pe *ProofError
+	if !errors.As(err, &pe) || pe.Address != common.HexToAddress("0x2") {
+		t.Fatalf("expected a ProofError for 0x2, got %v", err)
+	}
+}
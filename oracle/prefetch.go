@@ -1,55 +1,19 @@
 package oracle
 
 import (
-	"bytes"
-	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"io/ioutil"
-	"log"
 	"math/big"
-	"net/http"
-	"os"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
-type jsonreq struct {
-	Jsonrpc string        `json:"jsonrpc"`
-	Method  string        `json:"method"`
-	Params  []interface{} `json:"params"`
-	Id      uint64        `json:"id"`
-}
-
-type jsonresp struct {
-	Jsonrpc string        `json:"jsonrpc"`
-	Id      uint64        `json:"id"`
-	Result  AccountResult `json:"result"`
-}
-
-type jsonresps struct {
-	Jsonrpc string `json:"jsonrpc"`
-	Id      uint64 `json:"id"`
-	Result  string `json:"result"`
-}
-
-type jsonrespi struct {
-	Jsonrpc string         `json:"jsonrpc"`
-	Id      uint64         `json:"id"`
-	Result  hexutil.Uint64 `json:"result"`
-}
-
-type jsonrespt struct {
-	Jsonrpc string `json:"jsonrpc"`
-	Id      uint64 `json:"id"`
-	Result  Header `json:"result"`
-}
-
 // Result structs for GetProof
 type AccountResult struct {
 	Address      common.Address  `json:"address"`
@@ -76,260 +40,382 @@ type Account struct {
 	CodeHash []byte
 }
 
-var NodeUrl = "https://mainnet.infura.io/v3/9aa3d95b3bc440fa88ea12eaa4456161"
 var RemoteUrl = "https://mainnet.infura.io/v3/9aa3d95b3bc440fa88ea12eaa4456161"
 var LocalUrl = "http://localhost:8545"
 
-// For generating special tests for MPT circuit:
-var PreventHashingInSecureTrie = false
+// RPCOracle is an Oracle which obtains proofs, code and block headers from
+// a node via JSON-RPC.
+type RPCOracle struct {
+	NodeUrl string
+
+	// Transport sends the requests, when nil it is created by NewTransport from NodeUrl:
+	// HTTP, WebSocket or IPC depending on the URL scheme.
+	Transport Transport
+
+	// CacheMode and CacheDir configure recording and replaying of the JSON-RPC traffic.
+	CacheMode CacheMode
+	CacheDir  string
+
+	// EnvError is the error of the configuration read from the environment by NewRPCOracle
+	// (an unknown MPT_ORACLE_CACHE_MODE), the oracle then falls back to Passthrough but
+	// the requests fail with EnvError until it is cleared.
+	EnvError error
+
+	// Retries is the number of times a request is resent after a transport error or
+	// a 5xx/429 HTTP status, the first retry waits RetryBackoff, each next one twice as long.
+	Retries      int
+	RetryBackoff time.Duration
+
+	// For generating special tests for MPT circuit:
+	PreventHashing bool
+
+	// Store keeps the fetched preimages, it is in memory unless another store is given
+	// with WithPreimageStore. Close closes it.
+	Store PreimageStore
+
+	// FetchNode is called for a preimage which isn't in Store, e.g. the sibling of
+	// a deleted node that collapses a branch when the sibling wasn't part of any proof.
+	// When nil, the preimage is requested with the NodeMethod JSON-RPC method (debug_dbGet
+	// by default), an empty NodeMethod disables fetching.
+	FetchNode  NodeFetcher
+	NodeMethod string
+
+	// Workers is the number of concurrent requests sent by Prefetch.
+	Workers int
+
+	// lock protects the maps below, the oracle can be used by several goroutines
+	// (see Prefetch).
+	lock      sync.Mutex
+	cached    map[string]bool
+	unhashMap map[common.Hash]common.Address
+	roots     map[uint64]common.Hash // state roots by block number, for verifying the proofs
+	pinned    map[uint64]BlockRef    // the blocks fetched by PrefetchBlock, referred to by hash
+
+	// the account proofs fetched before PrefetchAccount was called for them
+	accountProofs map[string][]string
+}
+
+// Option configures the RPCOracle returned by NewRPCOracle.
+type Option func(*RPCOracle)
 
-func toFilename(key string) string {
-	return fmt.Sprintf("/tmp/eth/json_%s", key)
+// WithPreimageStore makes the oracle keep the preimages in store, which is owned by the
+// caller: closing the oracle doesn't close it, so it can be shared by several oracles.
+func WithPreimageStore(store PreimageStore) Option {
+	return func(o *RPCOracle) {
+		o.Store = sharedPreimageStore{store}
+	}
 }
 
-func cacheRead(key string) []byte {
-	dat, err := ioutil.ReadFile(toFilename(key))
-	if err == nil {
-		return dat
+// NewRPCOracle returns an RPCOracle which queries the node at nodeUrl.
+func NewRPCOracle(nodeUrl string, opts ...Option) *RPCOracle {
+	cacheMode, err := cacheModeFromEnv()
+	o := &RPCOracle{
+		NodeUrl:   nodeUrl,
+		CacheMode: cacheMode,
+		CacheDir:  cacheDirFromEnv(),
+		EnvError:  err,
+
+		Retries:      3,
+		RetryBackoff: 500 * time.Millisecond,
+
+		Store:      NewMemoryPreimageStore(),
+		NodeMethod: DefaultNodeMethod,
+		Workers:    DefaultWorkers,
+
+		cached:    make(map[string]bool),
+		unhashMap: make(map[common.Hash]common.Address),
+		roots:     make(map[uint64]common.Hash),
+		pinned:    make(map[uint64]BlockRef),
+
+		accountProofs: make(map[string][]string),
 	}
-	panic("cache missing")
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
 }
 
-func cacheExists(key string) bool {
-	_, err := os.Stat(toFilename(key))
-	return err == nil
+// NewMultiRPCOracle returns an RPCOracle which queries several nodes with the policy, see
+// MultiTransport.
+func NewMultiRPCOracle(nodeUrls []string, policy EndpointPolicy, opts ...Option) (*RPCOracle, error) {
+	t, err := NewMultiTransport(nodeUrls, policy)
+	if err != nil {
+		return nil, err
+	}
+	o := NewRPCOracle(nodeUrls[0], opts...)
+	o.Transport = t
+	return o, nil
 }
 
-func cacheWrite(key string, value []byte) {
-	ioutil.WriteFile(toFilename(key), value, 0644)
+func (o *RPCOracle) PreventHashingInSecureTrie() bool {
+	return o.PreventHashing
 }
 
-func getAPI(jsonData []byte) io.Reader {
-	key := hexutil.Encode(crypto.Keccak256(jsonData))
-	/* Note: switching between two testnets (to prepare tests with account in the first level)
-	if cacheExists(key) {
-		return bytes.NewReader(cacheRead(key))
-	}
-	*/
-	resp, _ := http.Post(NodeUrl, "application/json", bytes.NewBuffer(jsonData))
-	defer resp.Body.Close()
-	ret, _ := ioutil.ReadAll(resp.Body)
-	cacheWrite(key, ret)
-	return bytes.NewReader(ret)
+// unhash returns the address with the given hash, ok is false if the address hasn't
+// been seen in any proof request.
+func (o *RPCOracle) unhash(addrHash common.Hash) (addr common.Address, ok bool) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	addr, ok = o.unhashMap[addrHash]
+	return addr, ok
 }
 
-var unhashMap = make(map[common.Hash]common.Address)
+func (o *RPCOracle) setUnhash(addrHash common.Hash, addr common.Address) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.unhashMap[addrHash] = addr
+}
 
-func unhash(addrHash common.Hash) common.Address {
-	return unhashMap[addrHash]
+// isCached returns whether the request with the cache key has already succeeded.
+func (o *RPCOracle) isCached(key string) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.cached[key]
 }
 
-var cached = make(map[string]bool)
+func (o *RPCOracle) setCached(keys ...string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	for _, key := range keys {
+		o.cached[key] = true
+	}
+}
 
-func PrefetchStorage(blockNumber *big.Int, addr common.Address, skey common.Hash, postProcess func(map[common.Hash][]byte)) []string {
+func (o *RPCOracle) PrefetchStorage(blockNumber *big.Int, addr common.Address, skey common.Hash, postProcess func(map[common.Hash][]byte)) ([]string, error) {
 	key := fmt.Sprintf("proof_%d_%s_%s", blockNumber, addr, skey)
 	// TODO: should return proof anyway
-	if cached[key] {
-		return nil
+	if o.isCached(key) {
+		return nil, nil
 	}
-	cached[key] = true
 
-	ap := getProofAccount(blockNumber, addr, skey, true)
+	ap, err := o.getProofAccount(blockNumber, addr, skey, true)
+	if err != nil {
+		return nil, err
+	}
 	//fmt.Println("PrefetchStorage", blockNumber, addr, skey, len(ap))
-	newPreimages := make(map[common.Hash][]byte)
-	for _, s := range ap {
-		ret, _ := hex.DecodeString(s[2:])
-		hash := crypto.Keccak256Hash(ret)
-		//fmt.Println("   ", i, hash)
-		newPreimages[hash] = ret
+	newPreimages, err := proofPreimages("eth_getProof", ap)
+	if err != nil {
+		return nil, err
 	}
+	o.setCached(key)
 
 	if postProcess != nil {
 		postProcess(newPreimages)
 	}
 
-	for hash, val := range newPreimages {
-		preimages[hash] = val
+	if err := o.putPreimages(newPreimages); err != nil {
+		return nil, err
 	}
 
-	return ap
+	return ap, nil
 }
 
-func PrefetchAccount(blockNumber *big.Int, addr common.Address, postProcess func(map[common.Hash][]byte)) []string {
+func (o *RPCOracle) PrefetchAccount(blockNumber *big.Int, addr common.Address, postProcess func(map[common.Hash][]byte)) ([]string, error) {
 	key := fmt.Sprintf("proof_%d_%s", blockNumber, addr)
-	if cached[key] {
-		return nil
+	if o.isCached(key) {
+		return nil, nil
+	}
+	// The proof fetched by Prefetch (its nodes are stored already).
+	if postProcess == nil {
+		if ap, ok := o.takeAccountProof(key); ok {
+			return ap, nil
+		}
 	}
-	cached[key] = true
 
-	ap := getProofAccount(blockNumber, addr, common.Hash{}, false)
-	newPreimages := make(map[common.Hash][]byte)
-	for _, s := range ap {
-		ret, _ := hex.DecodeString(s[2:])
-		hash := crypto.Keccak256Hash(ret)
-		newPreimages[hash] = ret
-
-		/*
-			// just for debugging:
-			elems, _, err := rlp.SplitList(ret)
-			if err != nil {
-				fmt.Println("decode error", err)
-			}
-			switch c, _ := rlp.CountValues(elems); c {
-			case 2:
-				fmt.Println("2")
-			case 17:
-				fmt.Println("17")
-			default:
-				fmt.Println("invalid number of list elements")
-			}
-		*/
+	ap, err := o.getProofAccount(blockNumber, addr, common.Hash{}, false)
+	if err != nil {
+		return nil, err
 	}
+	newPreimages, err := proofPreimages("eth_getProof", ap)
+	if err != nil {
+		return nil, err
+	}
+	o.setCached(key)
 
 	if postProcess != nil {
 		postProcess(newPreimages)
 	}
 
-	for hash, val := range newPreimages {
-		preimages[hash] = val
+	if err := o.putPreimages(newPreimages); err != nil {
+		return nil, err
 	}
 
-	return ap
+	return ap, nil
 }
 
-func PrefetchCode(blockNumber *big.Int, addrHash common.Hash) {
-	key := fmt.Sprintf("code_%d_%s", blockNumber, addrHash)
-	if cached[key] {
-		return
+// proofPreimages decodes the hex-encoded proof nodes and maps them by their hashes.
+func proofPreimages(method string, proof []string) (map[common.Hash][]byte, error) {
+	newPreimages := make(map[common.Hash][]byte)
+	for _, s := range proof {
+		ret, err := hexutil.Decode(s)
+		if err != nil {
+			return nil, &MalformedResultError{Method: method, Err: fmt.Errorf("proof node %q: %v", s, err)}
+		}
+		newPreimages[crypto.Keccak256Hash(ret)] = ret
 	}
-	cached[key] = true
-	ret := getProvedCodeBytes(blockNumber, addrHash)
-	hash := crypto.Keccak256Hash(ret)
-	preimages[hash] = ret
+	return newPreimages, nil
 }
 
-var inputs [7]common.Hash
+// PrefetchBlock fetches the block header. The header of the start block is stored as
+// the preimage of the block hash, for the other blocks the transactions root is checked
+// against the transactions (see PrefetchBlockTransition for the transition to a block).
+//
+// The block is resolved once: the later proof and code requests for its number refer to
+// it by hash, so a reorg can't make them return the state of another block.
+func (o *RPCOracle) PrefetchBlock(block BlockRef, startBlock bool, hasher types.TrieHasher) (FullHeader, error) {
+	result, fullHeader, err := o.getBlock(block)
+	if err != nil {
+		return FullHeader{}, err
+	}
+
+	// put in the start block header
+	if startBlock {
+		if err := o.Store.Put(fullHeader.Hash(), fullHeader.RLP()); err != nil {
+			return FullHeader{}, err
+		}
+		return fullHeader, nil
+	}
 
-func Input(index int) common.Hash {
-	if index < 0 || index > 5 {
-		panic("bad input index")
+	if _, _, err := blockTransactions(result, hasher); err != nil {
+		return FullHeader{}, err
 	}
-	return inputs[index]
+	return fullHeader, nil
 }
 
-func Output(output common.Hash) {
-	if output == inputs[6] {
-		fmt.Println("good transition")
-	} else {
-		fmt.Println(output, "!=", inputs[5])
-		panic("BAD transition :((")
+// PrefetchBlockTransition fetches the block and its parent (the start block, its header
+// is stored as with PrefetchBlock) and checks that they are linked and that the
+// transactions match the transactions root of the block.
+func (o *RPCOracle) PrefetchBlockTransition(blockNumber *big.Int, hasher types.TrieHasher) (*BlockTransition, error) {
+	if blockNumber.Sign() <= 0 {
+		return nil, fmt.Errorf("block %d has no parent", blockNumber)
+	}
+	parentNumber := new(big.Int).Sub(blockNumber, common.Big1)
+	_, parentHeader, err := o.getBlock(BlockNumberRef(parentNumber))
+	if err != nil {
+		return nil, err
+	}
+	if err := o.Store.Put(parentHeader.Hash(), parentHeader.RLP()); err != nil {
+		return nil, err
 	}
-}
 
-func check(err error) {
+	result, blockHeader, err := o.getBlock(BlockNumberRef(blockNumber))
+	if err != nil {
+		return nil, err
+	}
+	if blockHeader.ParentHash != parentHeader.Hash() {
+		return nil, fmt.Errorf("block transition isn't correct: parent hash %s != %s", blockHeader.ParentHash, parentHeader.Hash())
+	}
+	txs, txHash, err := blockTransactions(result, hasher)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+
+	return &BlockTransition{
+		Parent:       parentHeader,
+		Header:       blockHeader,
+		Transactions: txs,
+		TxHash:       txHash,
+		PostRoot:     blockHeader.Root,
+	}, nil
 }
 
-func PrefetchBlock(blockNumber *big.Int, startBlock bool, hasher types.TrieHasher) types.Header {
+// getBlock fetches the block with its transactions and checks that the header hashes
+// to the block hash returned by the node. The block is pinned by its hash.
+func (o *RPCOracle) getBlock(block BlockRef) (*Header, FullHeader, error) {
 	r := jsonreq{Jsonrpc: "2.0", Method: "eth_getBlockByNumber", Id: 1}
 	r.Params = make([]interface{}, 2)
-	r.Params[0] = fmt.Sprintf("0x%x", blockNumber.Int64())
+	r.Params[0] = block
+	if block.Hash != nil {
+		r.Method = "eth_getBlockByHash"
+		r.Params[0] = *block.Hash
+	}
 	r.Params[1] = true
-	jsonData, _ := json.Marshal(r)
-
-	/*dat, _ := ioutil.ReadAll(getAPI(jsonData))
-	fmt.Println(string(dat))*/
 
-	jr := jsonrespt{}
-	check(json.NewDecoder(getAPI(jsonData)).Decode(&jr))
-	//fmt.Println(jr.Result)
-	// blockHeader := types.Header(jr.Result)
-	blockHeader := jr.Result.ToHeader()
-
-	// put in the start block header
-	if startBlock {
-		blockHeaderRlp, _ := rlp.EncodeToBytes(blockHeader)
-		hash := crypto.Keccak256Hash(blockHeaderRlp)
-		preimages[hash] = blockHeaderRlp
-		inputs[0] = hash
-		return blockHeader
+	var result Header
+	if err := o.call(r, &result); err != nil {
+		return nil, FullHeader{}, err
 	}
-
-	// second block
-	if blockHeader.ParentHash != Input(0) {
-		fmt.Println(blockHeader.ParentHash, Input(0))
-		panic("block transition isn't correct")
+	if result.Hash == nil {
+		return nil, FullHeader{}, &MalformedResultError{Method: r.Method, Err: errors.New("no block hash")}
 	}
-	inputs[1] = blockHeader.TxHash
-	inputs[2] = blockHeader.Coinbase.Hash()
-	inputs[3] = blockHeader.UncleHash
-	inputs[4] = common.BigToHash(big.NewInt(int64(blockHeader.GasLimit)))
-	inputs[5] = common.BigToHash(big.NewInt(int64(blockHeader.Time)))
-
-	// secret input
-	inputs[6] = blockHeader.Root
+	if block.Hash != nil && *result.Hash != *block.Hash {
+		return nil, FullHeader{}, &MalformedResultError{Method: r.Method, Err: fmt.Errorf("block %s returned for %s", result.Hash, block.Hash)}
+	}
+	// types.Header doesn't know the fields added after London, the full header is needed
+	// for the hash.
+	fullHeader := result.ToFullHeader()
+	if hash := fullHeader.Hash(); hash != *result.Hash {
+		return nil, FullHeader{}, &HeaderHashError{BlockNumber: fullHeader.Number, Hash: *result.Hash, Computed: hash}
+	}
+	number := fullHeader.Number.Uint64()
+	o.lock.Lock()
+	o.roots[number] = fullHeader.Root
+	o.pinned[number] = BlockHashRef(*result.Hash, block.RequireCanonical)
+	o.lock.Unlock()
+	return &result, fullHeader, nil
+}
 
-	// save the inputs
-	saveinput := make([]byte, 0)
-	for i := 0; i < len(inputs); i++ {
-		saveinput = append(saveinput, inputs[i].Bytes()[:]...)
+// blockRef returns the reference to the block with the given number used in the
+// requests: the block hash if the block was fetched, the number otherwise.
+func (o *RPCOracle) blockRef(blockNumber *big.Int) BlockRef {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if ref, ok := o.pinned[blockNumber.Uint64()]; ok {
+		return ref
 	}
-	key := fmt.Sprintf("/tmp/eth/%d", blockNumber.Uint64()-1)
-	ioutil.WriteFile(key, saveinput, 0644)
+	return BlockNumberRef(blockNumber)
+}
 
-	// save the txs
-	txs := make([]*types.Transaction, len(jr.Result.Transactions))
-	for i := 0; i < len(jr.Result.Transactions); i++ {
-		txs[i] = jr.Result.Transactions[i].ToTransaction()
+// blockTransactions returns the transactions of the block and their root, which has to
+// be the transactions root of the header. The root is computed with hasher (with
+// a stack trie if hasher is nil).
+func blockTransactions(result *Header, hasher types.TrieHasher) (types.Transactions, common.Hash, error) {
+	txs := make(types.Transactions, len(result.Transactions))
+	for i := 0; i < len(result.Transactions); i++ {
+		txs[i] = result.Transactions[i].ToTransaction()
 	}
-	fmt.Println(txs[0].To())
-	testTxHash := types.DeriveSha(types.Transactions(txs), hasher)
-	if testTxHash != blockHeader.TxHash {
-		fmt.Println(testTxHash, "!=", blockHeader.TxHash)
-		panic("tx hash derived wrong")
+	if hasher == nil {
+		hasher = trie.NewStackTrie(nil)
 	}
-
-	return blockHeader
+	txHash := types.DeriveSha(txs, hasher)
+	if txHash != *result.TxHash {
+		return nil, common.Hash{}, fmt.Errorf("tx hash derived wrong: %s != %s", txHash, *result.TxHash)
+	}
+	return txs, txHash, nil
 }
 
-func getProofAccount(blockNumber *big.Int, addr common.Address, skey common.Hash, storage bool) []string {
+func (o *RPCOracle) getProofAccount(blockNumber *big.Int, addr common.Address, skey common.Hash, storage bool) ([]string, error) {
 	addrHash := crypto.Keccak256Hash(addr[:])
-	unhashMap[addrHash] = addr
+	o.setUnhash(addrHash, addr)
+
+	// The root the proof is verified against, resolved first so that a block the node
+	// doesn't have is reported as BlockNotFoundError whatever eth_getProof answers.
+	if _, err := o.stateRoot(blockNumber); err != nil {
+		return nil, err
+	}
 
 	r := jsonreq{Jsonrpc: "2.0", Method: "eth_getProof", Id: 1}
 	r.Params = make([]interface{}, 3)
 	r.Params[0] = addr
 	r.Params[1] = [1]common.Hash{skey}
-	r.Params[2] = fmt.Sprintf("0x%x", blockNumber.Int64())
-	jsonData, _ := json.Marshal(r)
-	jr := jsonresp{}
-	json.NewDecoder(getAPI(jsonData)).Decode(&jr)
+	r.Params[2] = o.blockRef(blockNumber)
+	var result AccountResult
+	if err := o.call(r, &result); err != nil {
+		return nil, err
+	}
+	if len(result.StorageProof) > 1 {
+		return nil, &MalformedResultError{Method: r.Method, Err: fmt.Errorf("%d storage proofs for %s, 1 requested", len(result.StorageProof), addr)}
+	}
+	if err := o.verifyProof(blockNumber, addr, []common.Hash{skey}, &result); err != nil {
+		return nil, err
+	}
 
 	if storage {
-		return jr.Result.StorageProof[0].Proof
+		if len(result.StorageProof) == 0 {
+			return nil, &MalformedResultError{Method: r.Method, Err: fmt.Errorf("no storage proof for %s key %s", addr, skey)}
+		}
+		return result.StorageProof[0].Proof, nil
 	} else {
-		return jr.Result.AccountProof
+		return result.AccountProof, nil
 	}
 }
-
-func getProvedCodeBytes(blockNumber *big.Int, addrHash common.Hash) []byte {
-	addr := unhash(addrHash)
-
-	r := jsonreq{Jsonrpc: "2.0", Method: "eth_getCode", Id: 1}
-	r.Params = make([]interface{}, 2)
-	r.Params[0] = addr
-	r.Params[1] = fmt.Sprintf("0x%x", blockNumber.Int64())
-	jsonData, _ := json.Marshal(r)
-	jr := jsonresps{}
-	json.NewDecoder(getAPI(jsonData)).Decode(&jr)
-
-	//fmt.Println(jr.Result)
-
-	// curl -X POST --data '{"jsonrpc":"2.0","method":"eth_getCode","params":["0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b", "0x2"],"id":1}'
-
-	ret, _ := hex.DecodeString(jr.Result[2:])
-	//fmt.Println(ret)
-	return ret
-}
@@ -1,21 +1,24 @@
 package oracle
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
-var preimages = make(map[common.Hash][]byte)
-
-func Preimage(hash common.Hash) []byte {
-	val, ok := preimages[hash]
-	key := fmt.Sprintf("/tmp/eth/%s", hash)
-	ioutil.WriteFile(key, val, 0644)
-	if !ok {
-		fmt.Println("can't find preimage", hash)
+func (o *RPCOracle) Preimage(hash common.Hash) []byte {
+	val, err := o.Store.Get(hash)
+	if errors.Is(err, ErrPreimageNotFound) {
+		if val, err = o.fetchNode(hash); err != nil {
+			fmt.Println("can't find preimage", hash, err)
+			return nil
+		}
+	} else if err != nil {
+		// A failure of the store isn't a missing preimage, the node isn't fetched.
+		fmt.Println("can't read preimage", hash, err)
+		return nil
 	}
 	comphash := crypto.Keccak256Hash(val)
 	if hash != comphash {
@@ -24,13 +27,33 @@ func Preimage(hash common.Hash) []byte {
 	return val
 }
 
-// TODO: Maybe we will want to have a seperate preimages for next block's preimages?
-func Preimages() map[common.Hash][]byte {
-	return preimages
+func (o *RPCOracle) PutPreimage(hash common.Hash, preimage []byte) error {
+	return o.Store.Put(hash, preimage)
+}
+
+func (o *RPCOracle) putPreimages(preimages map[common.Hash][]byte) error {
+	for hash, val := range preimages {
+		if err := o.Store.Put(hash, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the connection to the node and the preimage store.
+func (o *RPCOracle) Close() error {
+	if o.Transport != nil {
+		if err := o.Transport.Close(); err != nil {
+			return err
+		}
+	}
+	return o.Store.Close()
 }
 
 // KeyValueWriter wraps the Put method of a backing data store.
-type PreimageKeyValueWriter struct{}
+type PreimageKeyValueWriter struct {
+	Oracle Oracle
+}
 
 // Put inserts the given value into the key-value data store.
 func (kw PreimageKeyValueWriter) Put(key []byte, value []byte) error {
@@ -38,9 +61,8 @@ func (kw PreimageKeyValueWriter) Put(key []byte, value []byte) error {
 	if hash != common.BytesToHash(key) {
 		panic("bad preimage value write")
 	}
-	preimages[hash] = common.CopyBytes(value)
 	// fmt.Println("tx preimage", hash, common.Bytes2Hex(value))
-	return nil
+	return kw.Oracle.PutPreimage(hash, value)
 }
 
 // Delete removes the key from the key-value data store.
@@ -0,0 +1,157 @@
+package oracle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errEmptyResult is the error of MalformedResultError for a null result, which the node
+// returns e.g. for a block it doesn't have.
+var errEmptyResult = errors.New("empty result")
+
+type jsonreq struct {
+	Jsonrpc string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+	Id      uint64        `json:"id"`
+}
+
+type jsonresp struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	Id      uint64          `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *jsonerror      `json:"error"`
+}
+
+type jsonerror struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// decode checks the JSON-RPC error object and decodes the result into result.
+func (jr *jsonresp) decode(method string, result interface{}) error {
+	if jr.Error != nil {
+		return &RPCError{Method: method, Code: jr.Error.Code, Message: jr.Error.Message, Data: jr.Error.Data}
+	}
+	if len(jr.Result) == 0 || bytes.Equal(jr.Result, []byte("null")) {
+		return &MalformedResultError{Method: method, Err: errEmptyResult}
+	}
+	if err := json.Unmarshal(jr.Result, result); err != nil {
+		return &MalformedResultError{Method: method, Err: err}
+	}
+	return nil
+}
+
+// call sends the request to the node and decodes its result into result.
+func (o *RPCOracle) call(r jsonreq, result interface{}) error {
+	jsonData, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	ret, err := o.getAPI(jsonData)
+	if err != nil {
+		return err
+	}
+	var jr jsonresp
+	if err := json.Unmarshal(ret, &jr); err != nil {
+		return &MalformedResultError{Method: r.Method, Err: err}
+	}
+	return jr.decode(r.Method, result)
+}
+
+// callBatch sends the requests as a JSON-RPC batch and returns the responses in the
+// order of reqs (the responses in a batch can come in any order).
+func (o *RPCOracle) callBatch(reqs []jsonreq) ([]jsonresp, error) {
+	jsonData, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := o.getAPI(jsonData)
+	if err != nil {
+		return nil, err
+	}
+	var jrs []jsonresp
+	if err := json.Unmarshal(ret, &jrs); err != nil {
+		// A node that doesn't support batches responds with a single error object.
+		var jr jsonresp
+		if json.Unmarshal(ret, &jr) == nil && jr.Error != nil {
+			return nil, jr.decode(reqs[0].Method, nil)
+		}
+		return nil, &MalformedResultError{Method: reqs[0].Method, Err: err}
+	}
+
+	byId := make(map[uint64]jsonresp, len(jrs))
+	for _, jr := range jrs {
+		byId[jr.Id] = jr
+	}
+	resps := make([]jsonresp, len(reqs))
+	for i, r := range reqs {
+		jr, ok := byId[r.Id]
+		if !ok {
+			return nil, &MalformedResultError{Method: r.Method, Err: fmt.Errorf("no response for request %d in the batch", r.Id)}
+		}
+		resps[i] = jr
+	}
+	return resps, nil
+}
+
+// getAPI sends jsonData to the node (or reads the response from the fixtures in the
+// replay mode). Transport errors and 5xx/429 HTTP statuses are retried with exponential
+// backoff, the fixture errors are returned like them.
+func (o *RPCOracle) getAPI(jsonData []byte) ([]byte, error) {
+	if o.EnvError != nil {
+		return nil, o.EnvError
+	}
+	key := o.fixtureKey(jsonData)
+	if o.CacheMode == Replay {
+		return o.cacheRead(key, jsonData)
+	}
+
+	var ret []byte
+	var err error
+	backoff := o.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		ret, err = o.post(jsonData)
+		if err == nil || attempt >= o.Retries || !retryable(err) {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if o.CacheMode == Record {
+		if err := o.cacheWrite(key, jsonData, ret); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func (o *RPCOracle) post(jsonData []byte) ([]byte, error) {
+	t, err := o.transport()
+	if err != nil {
+		return nil, err
+	}
+	return t.Send(jsonData)
+}
+
+// transport returns o.Transport, creating it from NodeUrl on the first request.
+func (o *RPCOracle) transport() (Transport, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.Transport == nil {
+		t, err := NewTransport(o.NodeUrl)
+		if err != nil {
+			return nil, err
+		}
+		o.Transport = t
+	}
+	return o.Transport, nil
+}
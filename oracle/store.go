@@ -0,0 +1,151 @@
+package oracle
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	leveldberrors "github.com/syndtr/goleveldb/leveldb/errors"
+)
+
+// PreimageStore keeps the preimages (trie nodes, contract code, block headers) by
+// their keccak256 hashes. The stores are safe for concurrent use.
+type PreimageStore interface {
+	// Get returns the preimage of hash, ErrPreimageNotFound if it isn't in the store.
+	Get(hash common.Hash) (preimage []byte, err error)
+
+	// Put stores the preimage of hash.
+	Put(hash common.Hash, preimage []byte) error
+
+	// Close releases the resources held by the store.
+	Close() error
+}
+
+// ErrPreimageNotFound is returned by PreimageStore.Get for a preimage which isn't in the
+// store, the other errors are failures of the store.
+var ErrPreimageNotFound = errors.New("preimage not found")
+
+// The LevelDB cache size (MB) and the number of file handles.
+const (
+	preimageDBCache   = 16
+	preimageDBHandles = 16
+)
+
+// MemoryPreimageStore keeps the preimages in a map.
+type MemoryPreimageStore struct {
+	preimages map[common.Hash][]byte
+	lock      sync.RWMutex
+}
+
+func NewMemoryPreimageStore() *MemoryPreimageStore {
+	return &MemoryPreimageStore{preimages: make(map[common.Hash][]byte)}
+}
+
+func (s *MemoryPreimageStore) Get(hash common.Hash) ([]byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	val, ok := s.preimages[hash]
+	if !ok {
+		return nil, ErrPreimageNotFound
+	}
+	return val, nil
+}
+
+func (s *MemoryPreimageStore) Put(hash common.Hash, preimage []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.preimages[hash] = common.CopyBytes(preimage)
+	return nil
+}
+
+func (s *MemoryPreimageStore) Close() error {
+	return nil
+}
+
+// DBPreimageStore keeps the preimages in a go-ethereum key-value database, the
+// preimages fetched in one run can thus be reused by the later runs.
+type DBPreimageStore struct {
+	db ethdb.KeyValueStore
+}
+
+// NewDBPreimageStore returns a store backed by db. The store owns db and closes it
+// in Close.
+func NewDBPreimageStore(db ethdb.KeyValueStore) *DBPreimageStore {
+	return &DBPreimageStore{db: db}
+}
+
+// OpenLevelDBPreimageStore opens (or creates) the LevelDB database in dir. A read-only
+// store never writes to the database, the preimages put into it are kept in memory.
+func OpenLevelDBPreimageStore(dir string, readOnly bool) (PreimageStore, error) {
+	db, err := leveldb.New(dir, preimageDBCache, preimageDBHandles, "mpt/preimages/", readOnly)
+	if err != nil {
+		return nil, err
+	}
+	store := NewDBPreimageStore(db)
+	if readOnly {
+		return NewReadOnlyPreimageStore(store), nil
+	}
+	return store, nil
+}
+
+func (s *DBPreimageStore) Get(hash common.Hash) ([]byte, error) {
+	val, err := s.db.Get(hash[:])
+	switch {
+	case err == nil:
+		return val, nil
+	case errors.Is(err, leveldberrors.ErrNotFound):
+		return nil, ErrPreimageNotFound
+	}
+	// The other databases have their own error for a missing key, Has tells it from
+	// a failure of the database (e.g. a closed or corrupted one).
+	if ok, hasErr := s.db.Has(hash[:]); hasErr == nil && !ok {
+		return nil, ErrPreimageNotFound
+	}
+	return nil, err
+}
+
+func (s *DBPreimageStore) Put(hash common.Hash, preimage []byte) error {
+	return s.db.Put(hash[:], preimage)
+}
+
+func (s *DBPreimageStore) Close() error {
+	return s.db.Close()
+}
+
+// ReadOnlyPreimageStore reads the preimages from an underlying store which it never
+// modifies, the preimages put into it are kept in memory.
+type ReadOnlyPreimageStore struct {
+	base    PreimageStore
+	overlay *MemoryPreimageStore
+}
+
+func NewReadOnlyPreimageStore(base PreimageStore) *ReadOnlyPreimageStore {
+	return &ReadOnlyPreimageStore{base: base, overlay: NewMemoryPreimageStore()}
+}
+
+func (s *ReadOnlyPreimageStore) Get(hash common.Hash) ([]byte, error) {
+	if val, err := s.overlay.Get(hash); err == nil {
+		return val, nil
+	}
+	return s.base.Get(hash)
+}
+
+func (s *ReadOnlyPreimageStore) Put(hash common.Hash, preimage []byte) error {
+	return s.overlay.Put(hash, preimage)
+}
+
+func (s *ReadOnlyPreimageStore) Close() error {
+	return s.base.Close()
+}
+
+// sharedPreimageStore is a store used by several oracles, closing an oracle doesn't close
+// the store.
+type sharedPreimageStore struct {
+	PreimageStore
+}
+
+func (s sharedPreimageStore) Close() error {
+	return nil
+}
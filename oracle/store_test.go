@@ -0,0 +1,102 @@
+package oracle
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestLevelDBPreimageStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oracle-preimages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := newTestState(map[common.Address][]common.Hash{common.HexToAddress("0x1"): nil, common.HexToAddress("0x2"): nil})
+	server := s.server(make(map[string]int))
+	hash := s.trie.Hash()
+
+	o := testOracle(server.URL)
+	if o.Store, err = OpenLevelDBPreimageStore(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.PrefetchAccount(big.NewInt(1), common.HexToAddress("0x1"), nil); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next run finds the node in the database without contacting the node.
+	o = testOracle(server.URL)
+	if o.Store, err = OpenLevelDBPreimageStore(dir, true); err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	if o.Preimage(hash) == nil {
+		t.Fatal("preimage not persisted")
+	}
+	if o.Preimage(common.HexToHash("0x1")) != nil {
+		t.Fatal("unknown preimage found")
+	}
+}
+
+func TestClosedPreimageStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oracle-preimages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := OpenLevelDBPreimageStore(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(common.HexToHash("0x1")); !errors.Is(err, ErrPreimageNotFound) {
+		t.Fatalf("expected ErrPreimageNotFound, got %v", err)
+	}
+	store.Close()
+
+	// The failure of the database isn't a missing preimage, the node isn't fetched.
+	if _, err := store.Get(common.HexToHash("0x1")); err == nil || errors.Is(err, ErrPreimageNotFound) {
+		t.Fatalf("expected the error of the closed database, got %v", err)
+	}
+	o := testOracle(LocalUrl)
+	o.Store = store
+	o.FetchNode = func(hash common.Hash) ([]byte, error) {
+		t.Fatal("node fetched for a failure of the store")
+		return nil, nil
+	}
+	if o.Preimage(common.HexToHash("0x1")) != nil {
+		t.Fatal("preimage found in a closed store")
+	}
+}
+
+func TestReadOnlyPreimageStore(t *testing.T) {
+	base := NewMemoryPreimageStore()
+	stored := []byte{1, 2, 3}
+	base.Put(crypto.Keccak256Hash(stored), stored)
+
+	store := NewReadOnlyPreimageStore(base)
+	added := []byte{4, 5, 6}
+	if err := store.Put(crypto.Keccak256Hash(added), added); err != nil {
+		t.Fatal(err)
+	}
+
+	if val, err := store.Get(crypto.Keccak256Hash(stored)); err != nil || !bytes.Equal(val, stored) {
+		t.Error("preimage from the underlying store not found")
+	}
+	if val, err := store.Get(crypto.Keccak256Hash(added)); err != nil || !bytes.Equal(val, added) {
+		t.Error("added preimage not found")
+	}
+	if _, err := base.Get(crypto.Keccak256Hash(added)); !errors.Is(err, ErrPreimageNotFound) {
+		t.Error("underlying store modified")
+	}
+}
@@ -0,0 +1,176 @@
+package oracle
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// PrestateAccount is an account in the result of the prestateTracer. In the diff mode
+// the fields which haven't changed are omitted from the post state, as are the storage
+// slots which have been cleared.
+type PrestateAccount struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   *uint64                     `json:"nonce,omitempty"`
+	Code    *hexutil.Bytes              `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+
+	// Recreated is set by MergePrestateDiffs on an account in after which existed before
+	// the block, was destructed by a transaction and created again by a later one: its
+	// fields and storage are those of the new account.
+	Recreated bool `json:"-"`
+}
+
+// PrestateDiff is the result of the prestateTracer in the diff mode for a transaction:
+// the touched accounts before and after the transaction. An account which has been
+// created is not in Pre, an account which has been destructed is not in Post.
+type PrestateDiff struct {
+	Pre  map[common.Address]*PrestateAccount `json:"pre"`
+	Post map[common.Address]*PrestateAccount `json:"post"`
+}
+
+type traceResult struct {
+	TxHash common.Hash   `json:"txHash"`
+	Result *PrestateDiff `json:"result"`
+	Error  string        `json:"error"`
+}
+
+// TracePrestateDiff returns the state changes of the transactions of the block, as
+// reported by debug_traceBlockByNumber (debug_traceBlockByHash for the blocks fetched by
+// PrefetchBlock) with the prestateTracer in the diff mode.
+func (o *RPCOracle) TracePrestateDiff(blockNumber *big.Int) ([]PrestateDiff, error) {
+	r := jsonreq{Jsonrpc: "2.0", Method: "debug_traceBlockByNumber", Id: 1}
+	r.Params = make([]interface{}, 2)
+	ref := o.blockRef(blockNumber)
+	r.Params[0] = ref
+	if ref.Hash != nil {
+		r.Method = "debug_traceBlockByHash"
+		r.Params[0] = *ref.Hash
+	}
+	r.Params[1] = map[string]interface{}{
+		"tracer":       "prestateTracer",
+		"tracerConfig": map[string]interface{}{"diffMode": true},
+	}
+
+	var results []traceResult
+	if err := o.call(r, &results); err != nil {
+		return nil, err
+	}
+	diffs := make([]PrestateDiff, len(results))
+	for i, res := range results {
+		if res.Error != "" {
+			return nil, &MalformedResultError{Method: r.Method, Err: fmt.Errorf("transaction %d (%s): %s", i, res.TxHash, res.Error)}
+		}
+		if res.Result == nil {
+			return nil, &MalformedResultError{Method: r.Method, Err: errors.New("no result")}
+		}
+		diffs[i] = *res.Result
+	}
+	return diffs, nil
+}
+
+// MergePrestateDiffs combines the diffs of the transactions of a block into the states
+// of the touched accounts before and after the block: the accounts in before are
+// complete except for the storage which contains only the touched slots, the slots
+// which have been cleared are zero in after. A nil account in before didn't exist before
+// the block, a nil account in after has been destructed, an account in after with
+// Recreated set replaces the destructed one.
+func MergePrestateDiffs(diffs []PrestateDiff) (before, after map[common.Address]*PrestateAccount) {
+	before = make(map[common.Address]*PrestateAccount)
+	after = make(map[common.Address]*PrestateAccount)
+	for _, diff := range diffs {
+		for addr, pre := range diff.Pre {
+			if _, seen := after[addr]; !seen {
+				before[addr] = pre.copy()
+				after[addr] = pre.copy()
+				continue
+			}
+			// The slots touched for the first time have their values from before the block.
+			if b, a := before[addr], after[addr]; b != nil && a != nil {
+				for key, val := range pre.Storage {
+					if _, touched := a.Storage[key]; !touched {
+						// The slots of a re-created account aren't the ones from before.
+						if !a.Recreated {
+							b.Storage[key] = val
+						}
+						a.Storage[key] = val
+					}
+				}
+			}
+		}
+
+		for addr, pre := range diff.Pre {
+			post, ok := diff.Post[addr]
+			if !ok {
+				after[addr] = nil
+				continue
+			}
+			a := after[addr]
+			if a == nil {
+				a = newPrestateAccount()
+				after[addr] = a
+			}
+			a.apply(post)
+			for key := range pre.Storage {
+				if _, ok := post.Storage[key]; !ok {
+					a.Storage[key] = common.Hash{}
+				}
+			}
+		}
+		for addr, post := range diff.Post {
+			if _, ok := diff.Pre[addr]; ok {
+				continue
+			}
+			// Created by the transaction.
+			prev, seen := after[addr]
+			if !seen {
+				before[addr] = nil
+			}
+			a := newPrestateAccount()
+			a.apply(post)
+			// The account which existed before the block was destructed by an earlier
+			// transaction, the new one replaces it.
+			a.Recreated = seen && prev == nil && before[addr] != nil
+			after[addr] = a
+		}
+	}
+	return before, after
+}
+
+func newPrestateAccount() *PrestateAccount {
+	nonce := uint64(0)
+	return &PrestateAccount{
+		Balance: new(hexutil.Big),
+		Nonce:   &nonce,
+		Code:    &hexutil.Bytes{},
+		Storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// copy returns a deep copy of the account with all fields set.
+func (acc *PrestateAccount) copy() *PrestateAccount {
+	cpy := newPrestateAccount()
+	cpy.apply(acc)
+	return cpy
+}
+
+// apply sets the fields which are set in update.
+func (acc *PrestateAccount) apply(update *PrestateAccount) {
+	if update.Balance != nil {
+		acc.Balance = (*hexutil.Big)(new(big.Int).Set(update.Balance.ToInt()))
+	}
+	if update.Nonce != nil {
+		nonce := *update.Nonce
+		acc.Nonce = &nonce
+	}
+	if update.Code != nil {
+		code := hexutil.Bytes(common.CopyBytes(*update.Code))
+		acc.Code = &code
+	}
+	for key, val := range update.Storage {
+		acc.Storage[key] = val
+	}
+}
@@ -0,0 +1,93 @@
+package oracle
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// traceResponse is a debug_traceBlockByNumber response of the prestateTracer in the diff
+// mode for a block with two transactions. The first one changes the balance, nonce and a
+// slot of 0x0a, the balance of 0x0b and creates 0x0c, the second one changes the nonce and
+// the slots of 0x0a (clearing 0x02) and destructs 0x0c.
+const traceResponse = `{"jsonrpc":"2.0","id":1,"result":[
+	{"txHash":"0x0000000000000000000000000000000000000000000000000000000000000001","result":{
+		"pre":{
+			"0x000000000000000000000000000000000000000a":{"balance":"0xa","nonce":1,"storage":{
+				"0x0000000000000000000000000000000000000000000000000000000000000001":"0x0000000000000000000000000000000000000000000000000000000000000011"}},
+			"0x000000000000000000000000000000000000000b":{"balance":"0x5"}},
+		"post":{
+			"0x000000000000000000000000000000000000000a":{"balance":"0x7","nonce":2,"storage":{
+				"0x0000000000000000000000000000000000000000000000000000000000000001":"0x0000000000000000000000000000000000000000000000000000000000000012"}},
+			"0x000000000000000000000000000000000000000b":{"balance":"0x8"},
+			"0x000000000000000000000000000000000000000c":{"balance":"0x1","code":"0x6001"}}}},
+	{"txHash":"0x0000000000000000000000000000000000000000000000000000000000000002","result":{
+		"pre":{
+			"0x000000000000000000000000000000000000000a":{"balance":"0x7","nonce":2,"storage":{
+				"0x0000000000000000000000000000000000000000000000000000000000000001":"0x0000000000000000000000000000000000000000000000000000000000000012",
+				"0x0000000000000000000000000000000000000000000000000000000000000002":"0x0000000000000000000000000000000000000000000000000000000000000022"}},
+			"0x000000000000000000000000000000000000000c":{"balance":"0x1","code":"0x6001"}},
+		"post":{
+			"0x000000000000000000000000000000000000000a":{"nonce":3,"storage":{
+				"0x0000000000000000000000000000000000000000000000000000000000000001":"0x0000000000000000000000000000000000000000000000000000000000000013"}}}}}
+]}`
+
+func TestTracePrestateDiff(t *testing.T) {
+	calls := 0
+	var params []json.RawMessage
+	server := respondWith(&calls, func(w http.ResponseWriter, r *http.Request) {
+		var req testRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		params = req.Params
+		body(traceResponse)(w, r)
+	})
+	defer server.Close()
+
+	o := testOracle(server.URL)
+	diffs, err := o.TracePrestateDiff(big.NewInt(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 2 || string(params[0]) != `"0x2"` || string(params[1]) != `{"tracer":"prestateTracer","tracerConfig":{"diffMode":true}}` {
+		t.Fatalf("unexpected trace request %s or %d results", params, len(diffs))
+	}
+
+	before, after := MergePrestateDiffs(diffs)
+	a, b, c := common.HexToAddress("0xa"), common.HexToAddress("0xb"), common.HexToAddress("0xc")
+	key1, key2 := common.HexToHash("0x1"), common.HexToHash("0x2")
+
+	if acc := before[a]; acc.Balance.ToInt().Int64() != 10 || *acc.Nonce != 1 ||
+		acc.Storage[key1] != common.HexToHash("0x11") || acc.Storage[key2] != common.HexToHash("0x22") {
+		t.Errorf("wrong state of 0x0a before the block: %+v", acc)
+	}
+	if acc := after[a]; acc.Balance.ToInt().Int64() != 7 || *acc.Nonce != 3 ||
+		acc.Storage[key1] != common.HexToHash("0x13") || acc.Storage[key2] != (common.Hash{}) {
+		t.Errorf("wrong state of 0x0a after the block: %+v", acc)
+	}
+	if before[b].Balance.ToInt().Int64() != 5 || after[b].Balance.ToInt().Int64() != 8 {
+		t.Errorf("wrong balance of 0x0b")
+	}
+	if acc, ok := before[c]; !ok || acc != nil {
+		t.Errorf("0x0c existed before the block")
+	}
+	if acc, ok := after[c]; !ok || acc != nil {
+		t.Errorf("0x0c not destructed")
+	}
+}
+
+func TestTracePrestateDiffError(t *testing.T) {
+	calls := 0
+	server := respondWith(&calls, body(`{"jsonrpc":"2.0","id":1,"result":[{"txHash":"0x0000000000000000000000000000000000000000000000000000000000000001","error":"execution timeout"}]}`))
+	defer server.Close()
+
+	o := testOracle(server.URL)
+	_, err := o.TracePrestateDiff(big.NewInt(2))
+	var me *MalformedResultError
+	if !errors.As(err, &me) {
+		t.Fatalf("expected a MalformedResultError, got %v", err)
+	}
+}
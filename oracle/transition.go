@@ -0,0 +1,25 @@
+package oracle
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// BlockTransition is the transition from the parent block to the block: applying
+// Transactions to the parent state has to give the state with the root PostRoot.
+type BlockTransition struct {
+	Parent       FullHeader
+	Header       FullHeader
+	Transactions types.Transactions
+	TxHash       common.Hash // derived from Transactions, equal to Header.TxHash
+	PostRoot     common.Hash // the state root of the block
+}
+
+// Check returns a *TransitionError if root, the state root after applying the
+// transactions, differs from the state root of the block.
+func (bt *BlockTransition) Check(root common.Hash) error {
+	if root != bt.PostRoot {
+		return &TransitionError{BlockNumber: bt.Header.Number, Root: root, Expected: bt.PostRoot}
+	}
+	return nil
+}
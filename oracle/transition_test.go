@@ -0,0 +1,130 @@
+package oracle
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// testBlocks returns a parent header and a block with a single legacy transaction.
+func testBlocks() (FullHeader, FullHeader, []interface{}) {
+	parent := londonHeader()
+	to := common.HexToAddress("0x05")
+	tx := types.NewTx(&types.LegacyTx{
+		Nonce:    1,
+		GasPrice: big.NewInt(10),
+		Gas:      21000,
+		To:       &to,
+		Value:    big.NewInt(100),
+		Data:     []byte{},
+		V:        big.NewInt(27),
+		R:        big.NewInt(1),
+		S:        big.NewInt(2),
+	})
+	block := londonHeader()
+	block.ParentHash = parent.Hash()
+	block.Number = big.NewInt(2)
+	block.Root = common.HexToHash("0x33")
+	block.TxHash = types.DeriveSha(types.Transactions{tx}, trie.NewStackTrie(nil))
+
+	v, r, s := tx.RawSignatureValues()
+	txs := []interface{}{map[string]interface{}{
+		"to":       to,
+		"nonce":    hexutil.Uint64(tx.Nonce()),
+		"gas":      hexutil.Uint64(tx.Gas()),
+		"gasPrice": (*hexutil.Big)(tx.GasPrice()),
+		"value":    (*hexutil.Big)(tx.Value()),
+		"input":    hexutil.Bytes(tx.Data()),
+		"v":        (*hexutil.Big)(v),
+		"r":        (*hexutil.Big)(r),
+		"s":        (*hexutil.Big)(s),
+	}}
+	return parent, block, txs
+}
+
+func TestBlockTransition(t *testing.T) {
+	parent, block, txs := testBlocks()
+	calls := 0
+	server := respondWith(&calls,
+		body(headerResponse(parent, parent.Hash())),
+		body(blockResponse(block, block.Hash(), txs)))
+	defer server.Close()
+
+	o := testOracle(server.URL)
+	bt, err := o.PrefetchBlockTransition(big.NewInt(2), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bt.Parent.Root != parent.Root || bt.Header.Number.Int64() != 2 || bt.PostRoot != block.Root {
+		t.Errorf("wrong headers in the transition")
+	}
+	if len(bt.Transactions) != 1 || bt.TxHash != block.TxHash {
+		t.Errorf("wrong transactions in the transition")
+	}
+	if o.Preimage(parent.Hash()) == nil {
+		t.Error("parent header not stored")
+	}
+
+	if err := bt.Check(block.Root); err != nil {
+		t.Error(err)
+	}
+	var te *TransitionError
+	if err := bt.Check(parent.Root); !errors.As(err, &te) || te.Root != parent.Root || te.Expected != block.Root {
+		t.Errorf("expected a TransitionError, got %v", err)
+	}
+}
+
+func TestInvalidBlockTransition(t *testing.T) {
+	for name, tamper := range map[string]func(block *FullHeader){
+		"wrong parent hash": func(block *FullHeader) {
+			block.ParentHash = common.HexToHash("0x1234")
+		},
+		"wrong transactions root": func(block *FullHeader) {
+			block.TxHash = types.EmptyRootHash
+		},
+	} {
+		parent, block, txs := testBlocks()
+		tamper(&block)
+		calls := 0
+		server := respondWith(&calls,
+			body(headerResponse(parent, parent.Hash())),
+			body(blockResponse(block, block.Hash(), txs)))
+
+		o := testOracle(server.URL)
+		if _, err := o.PrefetchBlockTransition(big.NewInt(2), nil); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		server.Close()
+	}
+}
+
+func TestBlockTransitionCancun(t *testing.T) {
+	parent, block, txs := testBlocks()
+	parent = cancunHeader()
+	block.ParentHash = parent.Hash()
+	block.WithdrawalsRoot, block.BlobGasUsed, block.ExcessBlobGas, block.ParentBeaconRoot =
+		parent.WithdrawalsRoot, parent.BlobGasUsed, parent.ExcessBlobGas, parent.ParentBeaconRoot
+	calls := 0
+	server := respondWith(&calls,
+		body(headerResponse(parent, parent.Hash())),
+		body(blockResponse(block, block.Hash(), txs)))
+	defer server.Close()
+
+	o := testOracle(server.URL)
+	bt, err := o.PrefetchBlockTransition(big.NewInt(2), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The headers keep the fields added after London.
+	if bt.Parent.Hash() != parent.Hash() || bt.Header.Hash() != block.Hash() {
+		t.Fatalf("headers hash to %s and %s, expected %s and %s", bt.Parent.Hash(), bt.Header.Hash(), parent.Hash(), block.Hash())
+	}
+	if bt.Header.Header().Hash() == block.Hash() {
+		t.Fatal("types.Header has the fields added after London")
+	}
+}
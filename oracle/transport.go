@@ -0,0 +1,165 @@
+package oracle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Transport sends an encoded JSON-RPC request (or batch) to the node and returns the
+// encoded response. It must be safe for concurrent use.
+type Transport interface {
+	Send(jsonData []byte) ([]byte, error)
+	Close() error
+}
+
+// NewTransport returns the transport for the node URL: HTTP for http:// and https://,
+// WebSocket for ws:// and wss://, and IPC for a path of a Unix socket (/path/geth.ipc).
+// The connection is established when the first request is sent.
+func NewTransport(nodeUrl string) (Transport, error) {
+	u, err := url.Parse(nodeUrl)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return &httpTransport{url: nodeUrl}, nil
+	case "ws", "wss":
+		return &wsTransport{url: nodeUrl}, nil
+	case "":
+		return &ipcTransport{path: nodeUrl}, nil
+	}
+	return nil, errors.New("unsupported node URL scheme " + u.Scheme)
+}
+
+// httpTransport posts each request.
+type httpTransport struct {
+	url string
+}
+
+func (t *httpTransport) Send(jsonData []byte) ([]byte, error) {
+	resp, err := http.Post(t.url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, &TransportError{Url: t.url, Err: err}
+	}
+	defer resp.Body.Close()
+	ret, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &TransportError{Url: t.url, Err: err}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &HTTPStatusError{Url: t.url, StatusCode: resp.StatusCode, Body: ret}
+	}
+	return ret, nil
+}
+
+func (t *httpTransport) Close() error {
+	return nil
+}
+
+// ipcTransport writes the requests to a Unix socket, the responses are read as JSON
+// values from the stream. A single request is in flight at a time so the responses
+// come in the order of the requests; after an error the connection is closed and the
+// next request opens a new one.
+type ipcTransport struct {
+	path string
+
+	lock sync.Mutex
+	conn net.Conn
+	dec  *json.Decoder
+}
+
+func (t *ipcTransport) Send(jsonData []byte) ([]byte, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.conn == nil {
+		conn, err := net.Dial("unix", t.path)
+		if err != nil {
+			return nil, &TransportError{Url: t.path, Err: err}
+		}
+		t.conn, t.dec = conn, json.NewDecoder(conn)
+	}
+	var ret json.RawMessage
+	_, err := t.conn.Write(jsonData)
+	if err == nil {
+		err = t.dec.Decode(&ret)
+	}
+	if err != nil {
+		t.closeConn()
+		return nil, &TransportError{Url: t.path, Err: err}
+	}
+	return ret, nil
+}
+
+func (t *ipcTransport) closeConn() error {
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn, t.dec = nil, nil
+	return err
+}
+
+func (t *ipcTransport) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.closeConn()
+}
+
+// wsTransport sends each request as a WebSocket text message and reads the next
+// message as its response, like ipcTransport one request at a time.
+type wsTransport struct {
+	url string
+
+	lock sync.Mutex
+	conn *websocket.Conn
+}
+
+func (t *wsTransport) Send(jsonData []byte) ([]byte, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.conn == nil {
+		conn, resp, err := websocket.DefaultDialer.Dial(t.url, nil)
+		if err != nil {
+			if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+				body, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				return nil, &HTTPStatusError{Url: t.url, StatusCode: resp.StatusCode, Body: body}
+			}
+			return nil, &TransportError{Url: t.url, Err: err}
+		}
+		t.conn = conn
+	}
+	err := t.conn.WriteMessage(websocket.TextMessage, jsonData)
+	var ret []byte
+	if err == nil {
+		_, ret, err = t.conn.ReadMessage()
+	}
+	if err != nil {
+		t.closeConn()
+		return nil, &TransportError{Url: t.url, Err: err}
+	}
+	return ret, nil
+}
+
+func (t *wsTransport) closeConn() error {
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
+
+func (t *wsTransport) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.closeConn()
+}
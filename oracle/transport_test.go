@@ -0,0 +1,143 @@
+package oracle
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/websocket"
+)
+
+// ipcServer answers the requests written to a Unix socket in dir, it returns the path
+// of the socket.
+func (s *testState) ipcServer(t *testing.T, dir string, calls map[string]int) string {
+	path := filepath.Join(dir, "geth.ipc")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				dec := json.NewDecoder(conn)
+				for {
+					var req json.RawMessage
+					if err := dec.Decode(&req); err != nil {
+						return
+					}
+					resp, err := s.respond(req, calls)
+					if err != nil {
+						return
+					}
+					conn.Write(resp)
+				}
+			}()
+		}
+	}()
+	return path
+}
+
+// wsServer answers the requests sent as WebSocket messages.
+func (s *testState) wsServer(calls map[string]int) *httptest.Server {
+	var upgrader websocket.Upgrader
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, req, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			resp, err := s.respond(req, calls)
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(websocket.TextMessage, resp)
+		}
+	}))
+}
+
+func TestTransports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oracle-ipc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := newVerifiedState()
+	calls := make(map[string]int)
+	httpServer := s.server(calls)
+	defer httpServer.Close()
+	wsServer := s.wsServer(calls)
+	defer wsServer.Close()
+
+	for _, nodeUrl := range []string{
+		httpServer.URL,
+		"ws" + strings.TrimPrefix(wsServer.URL, "http"),
+		s.ipcServer(t, dir, calls),
+	} {
+		o := testOracle(nodeUrl)
+		// Single requests and batches, sent concurrently.
+		err := o.Prefetch(big.NewInt(1), []StorageKeys{
+			{Address: verifiedAddr, Keys: []common.Hash{common.HexToHash("0x11"), verifiedKey}},
+			{Address: common.HexToAddress("0x1"), Keys: []common.Hash{common.HexToHash("0x1")}},
+			{Address: common.HexToAddress("0x2")},
+		})
+		if err != nil {
+			t.Errorf("%s: %v", nodeUrl, err)
+		}
+		if o.Preimage(s.trie.Hash()) == nil {
+			t.Errorf("%s: the state root node was not fetched", nodeUrl)
+		}
+		if err := o.Close(); err != nil {
+			t.Errorf("%s: %v", nodeUrl, err)
+		}
+	}
+}
+
+func TestTransportReconnects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oracle-ipc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	o := testOracle(filepath.Join(dir, "geth.ipc"))
+	defer o.Close()
+	_, err = o.PrefetchAccount(big.NewInt(1), verifiedAddr, nil)
+	var te *TransportError
+	if !errors.As(err, &te) {
+		t.Fatalf("expected a TransportError, got %v", err)
+	}
+
+	// The node is started after the first request failed.
+	s := newVerifiedState()
+	s.ipcServer(t, dir, make(map[string]int))
+	if _, err := o.PrefetchAccount(big.NewInt(1), verifiedAddr, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnsupportedScheme(t *testing.T) {
+	if _, err := NewTransport("ftp://localhost:8545"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
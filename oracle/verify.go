@@ -0,0 +1,153 @@
+package oracle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// emptyRoot is the root hash of an empty trie.
+var emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+// stateRoot returns the state root of the block, the header is fetched if the block
+// hasn't been seen by PrefetchBlock. BlockNotFoundError is returned if the node doesn't
+// have the block.
+func (o *RPCOracle) stateRoot(blockNumber *big.Int) (common.Hash, error) {
+	o.lock.Lock()
+	root, ok := o.roots[blockNumber.Uint64()]
+	o.lock.Unlock()
+	if ok {
+		return root, nil
+	}
+	r := jsonreq{Jsonrpc: "2.0", Method: "eth_getBlockByNumber", Id: 1}
+	r.Params = make([]interface{}, 2)
+	r.Params[0] = fmt.Sprintf("0x%x", blockNumber.Int64())
+	r.Params[1] = false
+
+	// Only the state root is decoded, the transactions are hashes here.
+	var result struct {
+		Root *common.Hash `json:"stateRoot"`
+	}
+	if err := o.call(r, &result); err != nil {
+		if errors.Is(err, errEmptyResult) {
+			return common.Hash{}, &BlockNotFoundError{BlockNumber: blockNumber}
+		}
+		return common.Hash{}, err
+	}
+	if result.Root == nil {
+		return common.Hash{}, &MalformedResultError{Method: r.Method, Err: errors.New("no state root")}
+	}
+	o.lock.Lock()
+	o.roots[blockNumber.Uint64()] = *result.Root
+	o.lock.Unlock()
+	return *result.Root, nil
+}
+
+// verifyProof checks the account proof of res against the state root of the block and
+// the storage proofs of keys against the storage root of the account.
+func (o *RPCOracle) verifyProof(blockNumber *big.Int, addr common.Address, keys []common.Hash, res *AccountResult) error {
+	root, err := o.stateRoot(blockNumber)
+	if err != nil {
+		return err
+	}
+
+	val, err := verifyProof(root, crypto.Keccak256(addr[:]), res.AccountProof)
+	if err != nil {
+		return &ProofError{Address: addr, BlockNumber: blockNumber, Err: err}
+	}
+	storageRoot := emptyRoot
+	if val != nil {
+		var account Account
+		if err := rlp.DecodeBytes(val, &account); err != nil {
+			return &ProofError{Address: addr, BlockNumber: blockNumber, Err: fmt.Errorf("invalid account: %v", err)}
+		}
+		if account.Root != res.StorageHash {
+			return &ProofError{Address: addr, BlockNumber: blockNumber,
+				Err: fmt.Errorf("storage hash %s differs from the proved storage root %s", res.StorageHash, account.Root)}
+		}
+		storageRoot = account.Root
+	}
+
+	for i, sp := range res.StorageProof {
+		key := keys[i]
+		val, err := verifyProof(storageRoot, crypto.Keccak256(key[:]), sp.Proof)
+		if err != nil {
+			return &ProofError{Address: addr, Key: &key, BlockNumber: blockNumber, Err: err}
+		}
+		var value []byte
+		if val != nil {
+			if _, value, _, err = rlp.Split(val); err != nil {
+				return &ProofError{Address: addr, Key: &key, BlockNumber: blockNumber, Err: fmt.Errorf("invalid value: %v", err)}
+			}
+		}
+		if sp.Value != nil && !bytes.Equal(value, sp.Value.ToInt().Bytes()) {
+			return &ProofError{Address: addr, Key: &key, BlockNumber: blockNumber,
+				Err: fmt.Errorf("value %s differs from the proved value %#x", sp.Value, value)}
+		}
+	}
+	return nil
+}
+
+// verifyProof returns the value at key proved by the hex-encoded proof nodes (nil
+// if the proof shows the key doesn't exist).
+func verifyProof(root common.Hash, key []byte, proof []string) ([]byte, error) {
+	if root == emptyRoot && len(proof) == 0 {
+		return nil, nil
+	}
+	proofDb := memorydb.New()
+	for _, s := range proof {
+		node, err := hexutil.Decode(s)
+		if err != nil {
+			return nil, &MalformedResultError{Method: "eth_getProof", Err: fmt.Errorf("proof node %q: %v", s, err)}
+		}
+		proofDb.Put(crypto.Keccak256(node), node)
+	}
+	return trie.VerifyProof(root, key, proofDb)
+}
+
+// StorageRoot returns the storage root of the account at addr in the state of the block,
+// read from the proof nodes which have been fetched before (e.g. by Prefetch). The root of
+// an account which doesn't exist is the empty root.
+func (o *RPCOracle) StorageRoot(blockNumber *big.Int, addr common.Address) (common.Hash, error) {
+	root, err := o.stateRoot(blockNumber)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	val, err := trie.VerifyProof(root, crypto.Keccak256(addr[:]), storeReader{o.Store})
+	if err != nil {
+		return common.Hash{}, &ProofError{Address: addr, BlockNumber: blockNumber, Err: err}
+	}
+	if val == nil {
+		return emptyRoot, nil
+	}
+	var account Account
+	if err := rlp.DecodeBytes(val, &account); err != nil {
+		return common.Hash{}, &ProofError{Address: addr, BlockNumber: blockNumber, Err: fmt.Errorf("invalid account: %v", err)}
+	}
+	return account.Root, nil
+}
+
+// storeReader gives trie.VerifyProof the proof nodes in the preimage store.
+type storeReader struct {
+	store PreimageStore
+}
+
+func (r storeReader) Has(key []byte) (bool, error) {
+	_, err := r.Get(key)
+	if errors.Is(err, ErrPreimageNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r storeReader) Get(key []byte) ([]byte, error) {
+	return r.store.Get(common.BytesToHash(key))
+}
@@ -0,0 +1,245 @@
+package oracle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// testState is a state built with the go-ethereum trie, the value of each storage
+// key is the key itself. Its server answers eth_getProof with valid proofs and
+// eth_getBlockByNumber with a header which has the state root, debug_dbGet with the
+// nodes of the tries.
+type testState struct {
+	db      *trie.Database
+	trie    *trie.Trie
+	storage map[common.Address]*trie.Trie
+
+	// tamper, when set, modifies the results before they are sent.
+	tamper func(*AccountResult)
+
+	// code is returned by eth_getCode (the code hash of the accounts is not changed).
+	code map[common.Address][]byte
+
+	lock sync.Mutex
+}
+
+func newTestState(accounts map[common.Address][]common.Hash) *testState {
+	db := trie.NewDatabase(memorydb.New())
+	s := &testState{db: db, storage: make(map[common.Address]*trie.Trie)}
+	s.trie, _ = trie.New(common.Hash{}, db)
+	for addr, keys := range accounts {
+		st, _ := trie.New(common.Hash{}, db)
+		for _, key := range keys {
+			v, _ := rlp.EncodeToBytes(common.TrimLeftZeroes(key[:]))
+			st.Update(crypto.Keccak256(key[:]), v)
+		}
+		st.Commit(nil)
+		s.storage[addr] = st
+		enc, _ := rlp.EncodeToBytes(&Account{Nonce: 1, Balance: big.NewInt(1), Root: st.Hash(), CodeHash: crypto.Keccak256(nil)})
+		s.trie.Update(crypto.Keccak256(addr[:]), enc)
+	}
+	s.trie.Commit(nil)
+	return s
+}
+
+type proofList [][]byte
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+func (n *proofList) Delete(key []byte) error {
+	panic("not supported")
+}
+
+func prove(t *trie.Trie, key []byte) []string {
+	var nodes proofList
+	t.Prove(key, 0, &nodes)
+	proof := []string{}
+	for _, node := range nodes {
+		proof = append(proof, hexutil.Encode(node))
+	}
+	return proof
+}
+
+func (s *testState) getProof(addr common.Address, keys []common.Hash) AccountResult {
+	res := AccountResult{Address: addr, AccountProof: prove(s.trie, crypto.Keccak256(addr[:])), StorageHash: emptyRoot}
+	st, ok := s.storage[addr]
+	if ok {
+		res.StorageHash = st.Hash()
+	}
+	for _, k := range keys {
+		sr := StorageResult{Key: k.Hex(), Value: new(hexutil.Big), Proof: []string{}}
+		if ok {
+			if val := st.Get(crypto.Keccak256(k[:])); val != nil {
+				sr.Value = (*hexutil.Big)(k.Big())
+			}
+			sr.Proof = prove(st, crypto.Keccak256(k[:]))
+		}
+		res.StorageProof = append(res.StorageProof, sr)
+	}
+	if s.tamper != nil {
+		s.tamper(&res)
+	}
+	return res
+}
+
+type testRequest struct {
+	Id     uint64            `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+func (s *testState) answer(req testRequest) jsonresp {
+	var res interface{}
+	switch req.Method {
+	case "eth_getBlockByNumber":
+		res = map[string]interface{}{"stateRoot": s.trie.Hash()}
+	case "eth_getProof":
+		var addr common.Address
+		var keys []common.Hash
+		json.Unmarshal(req.Params[0], &addr)
+		json.Unmarshal(req.Params[1], &keys)
+		res = s.getProof(addr, keys)
+	case "eth_getCode":
+		var addr common.Address
+		json.Unmarshal(req.Params[0], &addr)
+		res = hexutil.Bytes(s.code[addr])
+	case "debug_dbGet":
+		var hash common.Hash
+		json.Unmarshal(req.Params[0], &hash)
+		node, err := s.db.Node(hash)
+		if err != nil {
+			return jsonresp{Jsonrpc: "2.0", Id: req.Id, Error: &jsonerror{Code: -32000, Message: "leveldb: not found"}}
+		}
+		res = hexutil.Bytes(node)
+	}
+	result, _ := json.Marshal(res)
+	return jsonresp{Jsonrpc: "2.0", Id: req.Id, Result: result}
+}
+
+// respond answers the encoded single or batched request, the batches in reverse order.
+// calls counts the requests by the method of their first JSON-RPC request.
+func (s *testState) respond(body []byte, calls map[string]int) ([]byte, error) {
+	s.lock.Lock() // the requests are handled concurrently
+	defer s.lock.Unlock()
+	if bytes.HasPrefix(body, []byte("[")) {
+		var reqs []testRequest
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			return nil, err
+		}
+		calls[reqs[0].Method]++
+		var resps []jsonresp
+		// Answer in reverse order, batch responses don't need to keep the order.
+		for i := len(reqs) - 1; i >= 0; i-- {
+			resps = append(resps, s.answer(reqs[i]))
+		}
+		return json.Marshal(resps)
+	}
+	var req testRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	calls[req.Method]++
+	return json.Marshal(s.answer(req))
+}
+
+// server answers the requests sent over HTTP.
+func (s *testState) server(calls map[string]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		resp, err := s.respond(body, calls)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(resp)
+	}))
+}
+
+var (
+	verifiedAddr = common.HexToAddress("0xaaaccf12580138bc2bbceeeaa111df4e42ab81ff")
+	verifiedKey  = common.HexToHash("0x12")
+)
+
+func newVerifiedState() *testState {
+	return newTestState(map[common.Address][]common.Hash{
+		verifiedAddr:               {common.HexToHash("0x11"), verifiedKey},
+		common.HexToAddress("0x1"): {common.HexToHash("0x1")},
+		common.HexToAddress("0x2"): nil,
+	})
+}
+
+func TestVerifiedProofs(t *testing.T) {
+	s := newVerifiedState()
+	calls := make(map[string]int)
+	server := s.server(calls)
+	defer server.Close()
+	o := testOracle(server.URL)
+
+	if _, err := o.PrefetchStorage(big.NewInt(1), verifiedAddr, verifiedKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	// A non-existing key and a non-existing account.
+	if _, err := o.PrefetchStorage(big.NewInt(1), verifiedAddr, common.HexToHash("0x13"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.PrefetchAccount(big.NewInt(1), common.HexToAddress("0x3"), nil); err != nil {
+		t.Fatal(err)
+	}
+	// The header is fetched once for the block.
+	if calls["eth_getBlockByNumber"] != 1 {
+		t.Fatalf("expected one eth_getBlockByNumber call, got %d", calls["eth_getBlockByNumber"])
+	}
+}
+
+func TestInvalidProofs(t *testing.T) {
+	for name, tamper := range map[string]func(*AccountResult){
+		"account proof of another state": func(res *AccountResult) {
+			res.AccountProof = prove(newTestState(nil).trie, crypto.Keccak256(res.Address[:]))
+		},
+		"missing account proof node": func(res *AccountResult) {
+			res.AccountProof = res.AccountProof[1:]
+		},
+		"wrong storage hash": func(res *AccountResult) {
+			res.StorageHash = common.HexToHash("0x1234")
+		},
+		"storage proof for another key": func(res *AccountResult) {
+			res.StorageProof[0].Proof = prove(newVerifiedState().storage[verifiedAddr], crypto.Keccak256(common.HexToHash("0x11").Bytes()))
+		},
+		"wrong value": func(res *AccountResult) {
+			res.StorageProof[0].Value = (*hexutil.Big)(big.NewInt(7))
+		},
+	} {
+		s := newVerifiedState()
+		s.tamper = tamper
+		server := s.server(make(map[string]int))
+		o := testOracle(server.URL)
+		_, err := o.PrefetchStorage(big.NewInt(1), verifiedAddr, verifiedKey, nil)
+		server.Close()
+
+		var pe *ProofError
+		if !errors.As(err, &pe) || pe.Address != verifiedAddr || pe.BlockNumber.Int64() != 1 {
+			t.Errorf("%s: expected a ProofError, got %v", name, err)
+			continue
+		}
+		if o.Preimage(newVerifiedState().trie.Hash()) != nil {
+			t.Errorf("%s: the nodes of an invalid proof were stored", name)
+		}
+	}
+}
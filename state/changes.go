@@ -0,0 +1,196 @@
+package state
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AccountChange is the net change of an account recorded in the journal since the last
+// Finalise (or IntermediateRoot): for each field the value after its last change, when it
+// differs from the value before its first change.
+type AccountChange struct {
+	Address common.Address
+
+	// Created is set when the account didn't exist before the changes or was replaced by
+	// CreateAccount, the other fields are then relative to the new account (which has the
+	// balance of the replaced one).
+	Created bool
+	// Destructed is set when an account which existed before the changes self-destructed
+	// (also when it was replaced by CreateAccount first), the other fields are not set then.
+	// StorageWiped is set with it when the account had a non-empty storage.
+	Destructed   bool
+	StorageWiped bool
+
+	Nonce       *uint64  // nil if unchanged
+	Balance     *big.Int // nil if unchanged
+	CodeChanged bool
+	Code        []byte
+	Storage     []StorageChange // in the order of the first change of each key
+
+	// Touched is set for an empty account which was only touched (e.g. by a transfer of
+	// zero value), Finalise removes it when deleteEmptyObjects is set (EIP-161).
+	Touched bool
+}
+
+// StorageChange is the new value of a storage slot.
+type StorageChange struct {
+	Key   common.Hash
+	Value common.Hash
+}
+
+// journalAccount collects the values of the fields of an account before their first change.
+type journalAccount struct {
+	created bool
+	// replaced is set when the account existed before the changes and was replaced by
+	// CreateAccount, replacedStorage when it had a non-empty storage then.
+	replaced        bool
+	replacedStorage bool
+
+	nonce       *uint64
+	balance     *big.Int
+	codeChanged bool
+	code        []byte
+	storage     map[common.Hash]common.Hash
+	keys        []common.Hash
+}
+
+func newJournalAccount() *journalAccount {
+	return &journalAccount{storage: make(map[common.Hash]common.Hash)}
+}
+
+// newCreatedAccount returns the account which starts as a new account with the balance.
+func newCreatedAccount(balance *big.Int) *journalAccount {
+	var nonce uint64
+	a := newJournalAccount()
+	a.created = true
+	a.nonce, a.balance = &nonce, new(big.Int).Set(balance)
+	a.codeChanged = true
+	return a
+}
+
+// hasStorage returns whether the storage of the object is not empty, with the pending
+// changes which are not in the storage trie yet.
+func (s *stateObject) hasStorage() bool {
+	if s.data.Root != emptyRoot {
+		return true
+	}
+	for _, value := range s.pendingStorage {
+		if value != (common.Hash{}) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *journalAccount) setStorage(key, prev common.Hash) {
+	if _, ok := a.storage[key]; !ok {
+		a.storage[key] = prev
+		a.keys = append(a.keys, key)
+	}
+}
+
+// JournalChanges returns the net changes of the accounts in the journal, in the order of
+// the first change of each account. The changes of the reverted snapshots are not in the
+// journal anymore.
+func (s *StateDB) JournalChanges() []AccountChange {
+	accounts := make(map[common.Address]*journalAccount)
+	var addrs []common.Address
+	account := func(addr common.Address) *journalAccount {
+		a, ok := accounts[addr]
+		if !ok {
+			a = newJournalAccount()
+			accounts[addr] = a
+			addrs = append(addrs, addr)
+		}
+		return a
+	}
+	// The changes before the account was (re)created are replaced by the new account.
+	create := func(addr common.Address, balance *big.Int) *journalAccount {
+		account(addr)
+		accounts[addr] = newCreatedAccount(balance)
+		return accounts[addr]
+	}
+
+	for _, entry := range s.journal.entries {
+		switch ch := entry.(type) {
+		case createObjectChange:
+			create(*ch.account, new(big.Int))
+		case resetObjectChange:
+			// The replaced object existed before the changes unless it was deleted by
+			// an earlier Finalise or created by the changes.
+			addr := ch.prev.address
+			replaced, replacedStorage := !ch.prev.deleted, ch.prev.hasStorage()
+			if a, ok := accounts[addr]; ok && a.created {
+				replaced, replacedStorage = a.replaced, a.replacedStorage
+			}
+			a := create(addr, ch.prev.data.Balance)
+			a.replaced, a.replacedStorage = replaced, replacedStorage
+		case nonceChange:
+			if a := account(*ch.account); a.nonce == nil {
+				prev := ch.prev
+				a.nonce = &prev
+			}
+		case balanceChange:
+			if a := account(*ch.account); a.balance == nil {
+				a.balance = new(big.Int).Set(ch.prev)
+			}
+		case codeChange:
+			if a := account(*ch.account); !a.codeChanged {
+				a.codeChanged, a.code = true, ch.prevcode
+			}
+		case storageChange:
+			account(*ch.account).setStorage(ch.key, ch.prevalue)
+		case suicideChange:
+			account(*ch.account)
+		case touchChange:
+			account(*ch.account)
+		}
+	}
+
+	var changes []AccountChange
+	for _, addr := range addrs {
+		a, obj := accounts[addr], s.stateObjects[addr]
+		if obj == nil {
+			continue
+		}
+		change := AccountChange{Address: addr, Created: a.created}
+		if obj.suicided || obj.deleted {
+			if a.created && !a.replaced {
+				// Created and destructed, nothing changed.
+				continue
+			}
+			storage := obj.hasStorage()
+			if a.replaced {
+				// The destructed object is the new one, the storage of the replaced
+				// account is wiped.
+				storage = a.replacedStorage
+			}
+			changes = append(changes, AccountChange{Address: addr, Destructed: true, StorageWiped: storage})
+			continue
+		}
+		if a.nonce != nil && *a.nonce != obj.Nonce() {
+			nonce := obj.Nonce()
+			change.Nonce = &nonce
+		}
+		if a.balance != nil && a.balance.Cmp(obj.Balance()) != 0 {
+			change.Balance = new(big.Int).Set(obj.Balance())
+		}
+		if a.codeChanged && !bytes.Equal(a.code, obj.Code(s.Db)) {
+			change.CodeChanged, change.Code = true, obj.Code(s.Db)
+		}
+		for _, key := range a.keys {
+			if value := obj.GetState(s.Db, key); value != a.storage[key] {
+				change.Storage = append(change.Storage, StorageChange{Key: key, Value: value})
+			}
+		}
+		if change.Created || change.Nonce != nil || change.Balance != nil || change.CodeChanged || len(change.Storage) > 0 {
+			changes = append(changes, change)
+		} else if obj.empty() {
+			change.Touched = true
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
@@ -5,7 +5,6 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/privacy-scaling-explorations/mpt-witness-generator/oracle"
 	"github.com/privacy-scaling-explorations/mpt-witness-generator/trie"
@@ -18,26 +17,41 @@ type Database struct {
 	db          *trie.Database
 	BlockNumber *big.Int
 	StateRoot   common.Hash
+	Oracle      oracle.Oracle
 }
 
-func NewDatabase(header types.Header) Database {
+func NewDatabase(header oracle.FullHeader, o oracle.Oracle) (Database, error) {
 	//triedb := trie.Database{BlockNumber: header.Number, Root: header.Root}
 	//triedb.Preseed()
-	triedb := trie.NewDatabase(header)
-	return Database{db: &triedb, BlockNumber: header.Number, StateRoot: header.Root}
+	triedb, err := trie.NewDatabase(header, o)
+	if err != nil {
+		return Database{}, err
+	}
+	return Database{db: triedb, BlockNumber: header.Number, StateRoot: header.Root, Oracle: o}, nil
+}
+
+// NewMemoryDatabase returns a database which keeps the committed state in memory, no node
+// is needed (see NewFromAlloc).
+func NewMemoryDatabase() Database {
+	triedb := trie.NewMemoryDatabase()
+	return Database{db: triedb, BlockNumber: triedb.BlockNumber, StateRoot: triedb.Root, Oracle: triedb.Oracle}
 }
 
 // ContractCode retrieves a particular contract's code.
 func (db *Database) ContractCode(addrHash common.Hash, codeHash common.Hash) ([]byte, error) {
-	oracle.PrefetchCode(db.BlockNumber, addrHash)
-	code := oracle.Preimage(codeHash)
+	if err := db.Oracle.PrefetchCode(db.BlockNumber, addrHash, codeHash); err != nil {
+		return nil, err
+	}
+	code := db.Oracle.Preimage(codeHash)
 	return code, nil
 }
 
 // ContractCodeSize retrieves a particular contracts code's size.
 func (db *Database) ContractCodeSize(addrHash common.Hash, codeHash common.Hash) (int, error) {
-	oracle.PrefetchCode(db.BlockNumber, addrHash)
-	code := oracle.Preimage(codeHash)
+	if err := db.Oracle.PrefetchCode(db.BlockNumber, addrHash, codeHash); err != nil {
+		return 0, err
+	}
+	code := db.Oracle.Preimage(codeHash)
 	return len(code), nil
 }
 
@@ -101,6 +115,10 @@ type Trie interface {
 	GetNodeByNibbles(key []byte) ([]byte, error)
 
 	GetRoot() (trie.Node)
+
+	// NodeIterator returns an iterator that returns nodes of the trie. Iteration
+	// starts at the key after the given start key.
+	NodeIterator(startKey []byte) trie.NodeIterator
 }
 
 // stubbed: we don't prefetch
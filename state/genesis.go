@@ -0,0 +1,84 @@
+package state
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/trie"
+)
+
+// NewFromAlloc returns a StateDB on a memory database (see NewMemoryDatabase) with the
+// state given by a go-ethereum genesis alloc, so that witnesses for crafted trie shapes
+// can be generated without a node.
+func NewFromAlloc(alloc core.GenesisAlloc) (*StateDB, error) {
+	db := NewMemoryDatabase()
+	accounts, storage, err := AllocTries(alloc, db.db)
+	if err != nil {
+		return nil, err
+	}
+	for addr, account := range alloc {
+		if _, err := storage[addr].Commit(nil); err != nil {
+			return nil, err
+		}
+		if len(account.Code) > 0 {
+			if err := db.Oracle.PutPreimage(crypto.Keccak256Hash(account.Code), account.Code); err != nil {
+				return nil, err
+			}
+		}
+	}
+	root, err := accounts.Commit(nil)
+	if err != nil {
+		return nil, err
+	}
+	db.StateRoot = root
+	return New(root, db, nil)
+}
+
+// AllocTries builds the account trie and the storage tries of the accounts given by
+// a go-ethereum genesis alloc on db. The tries aren't committed and the code isn't
+// stored, this is up to the caller.
+func AllocTries(alloc core.GenesisAlloc, db *trie.Database) (*trie.Trie, map[common.Address]*trie.Trie, error) {
+	accounts, err := trie.New(common.Hash{}, db)
+	if err != nil {
+		return nil, nil, err
+	}
+	storage := make(map[common.Address]*trie.Trie, len(alloc))
+	for addr, account := range alloc {
+		st, err := trie.New(common.Hash{}, db)
+		if err != nil {
+			return nil, nil, err
+		}
+		for key, value := range account.Storage {
+			if value == (common.Hash{}) {
+				continue
+			}
+			// Encoding []byte cannot fail, ok to ignore the error.
+			v, _ := rlp.EncodeToBytes(common.TrimLeftZeroes(value[:]))
+			if err := st.TryUpdate(crypto.Keccak256(key[:]), v); err != nil {
+				return nil, nil, err
+			}
+		}
+		storage[addr] = st
+
+		data := Account{
+			Nonce:    account.Nonce,
+			Balance:  new(big.Int),
+			Root:     st.Hash(),
+			CodeHash: crypto.Keccak256(account.Code),
+		}
+		if account.Balance != nil {
+			data.Balance.Set(account.Balance)
+		}
+		enc, err := rlp.EncodeToBytes(&data)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := accounts.TryUpdate(crypto.Keccak256(addr[:]), enc); err != nil {
+			return nil, nil, err
+		}
+	}
+	return accounts, storage, nil
+}
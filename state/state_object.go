@@ -27,7 +27,6 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rlp"
-	"github.com/privacy-scaling-explorations/mpt-witness-generator/oracle"
 )
 
 var emptyCodeHash = crypto.Keccak256(nil)
@@ -91,6 +90,10 @@ type stateObject struct {
 	dirtyCode bool // true if the code was updated
 	suicided  bool
 	deleted   bool
+
+	// created is set for an object created since the last Finalise (in the current
+	// transaction), only such an object can self-destruct under EIP-6780.
+	created bool
 }
 
 // empty returns whether the account is considered empty.
@@ -237,8 +240,9 @@ func (s *stateObject) GetCommittedState(db Database, key common.Hash) common.Has
 		}
 		enc, err = s.db.snap.Storage(s.addrHash, crypto.Keccak256Hash(key.Bytes()))
 	}
-	// If snapshot unavailable or reading from it failed, load from the database
-	if s.db.snap == nil || err != nil {
+	// If snapshot unavailable or reading from it failed, load from the database. The
+	// storage of a new or wiped account starts empty, the proofs of the block don't apply.
+	if (s.db.snap == nil || err != nil) && s.data.Root != emptyRoot {
 		if meter != nil {
 			// If we already spent time checking the snapshot, account for it
 			// and reset the readStart
@@ -248,7 +252,10 @@ func (s *stateObject) GetCommittedState(db Database, key common.Hash) common.Has
 		if metrics.EnabledExpensive {
 			meter = &s.db.StorageReads
 		}
-		oracle.PrefetchStorage(db.BlockNumber, s.address, key, nil)
+		if _, err = db.Oracle.PrefetchStorage(db.BlockNumber, s.address, key, nil); err != nil {
+			s.setError(err)
+			return common.Hash{}
+		}
 		if enc, err = s.getTrie(db).TryGet(key.Bytes()); err != nil {
 			s.setError(err)
 			return common.Hash{}
@@ -359,7 +366,7 @@ func (s *stateObject) updateTrie(db Database) Trie {
 			// Get absense proof of key in case the deletion needs the sister node.
 
 			// Note: commented for now because of `ExtNodeDeleted`
-			// oracle.PrefetchStorage(big.NewInt(db.BlockNumber.Int64()+1), s.address, key, trie.GenPossibleShortNodePreimage)
+			// db.Oracle.PrefetchStorage(big.NewInt(db.BlockNumber.Int64()+1), s.address, key, trie.GenPossibleShortNodePreimage)
 			s.setError(tr.TryDelete(key[:]))
 		} else {
 			//fmt.Println("update", s.address, key, value)
@@ -143,7 +143,9 @@ func New(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error)
 		journal:             newJournal(),
 		accessList:          newAccessList(),
 		hasher:              crypto.NewKeccakState(),
-		loadRemoteAccountsIntoStateObjects: true,
+		// The proofs of the oracle are for the state of the block, they don't describe
+		// a state reopened at the root committed after some modifications.
+		loadRemoteAccountsIntoStateObjects: root == db.StateRoot,
 	}
 	/*if sdb.snaps != nil {
 		if sdb.snap = sdb.snaps.Snapshot(root); sdb.snap != nil {
@@ -316,7 +318,7 @@ func (s *StateDB) GetStorageProof(a common.Address, key common.Hash) ([][]byte,
 		return proof, nil, nil, false, errors.New("storage trie for requested address does not exist")
 	}
 	var newKey []byte
-	if !oracle.PreventHashingInSecureTrie {
+	if !s.Db.Oracle.PreventHashingInSecureTrie() {
 		newKey = crypto.Keccak256(key.Bytes())
 	} else {
 		newKey = key.Bytes()
@@ -434,7 +436,11 @@ func (s *StateDB) SetStorage(addr common.Address, storage map[common.Hash]common
 // is populated only with the objects that are created locally.
 func (s *StateDB) SetStateObjectIfExists(addr common.Address) {
 	if s.loadRemoteAccountsIntoStateObjects {
-		ap := oracle.PrefetchAccount(s.Db.BlockNumber, addr, nil)
+		ap, err := s.Db.Oracle.PrefetchAccount(s.Db.BlockNumber, addr, nil)
+		if err != nil {
+			s.setError(fmt.Errorf("SetStateObjectIfExists (%x) error: %v", addr[:], err))
+			return
+		}
 		if len(ap) > 0 {
 			ret, _ := hex.DecodeString(ap[len(ap)-1][2:])
 			s.setStateObjectFromEncoding(addr, ret)
@@ -463,13 +469,45 @@ func (s *StateDB) Suicide(addr common.Address) bool {
 	return true
 }
 
+// Selfdestruct6780 marks the given account as suicided only if it was created in the
+// current transaction (EIP-6780), otherwise the account and its storage are kept.
+// It is a helper for building the modifications by hand: the EVM (vm.StateDB of the
+// go-ethereum version in use, before Cancun) calls Suicide, it never calls this.
+func (s *StateDB) Selfdestruct6780(addr common.Address) bool {
+	stateObject := s.getStateObject(addr)
+	if stateObject == nil || !stateObject.created {
+		return false
+	}
+	return s.Suicide(addr)
+}
+
 // Added for MPT generator:
+// DeleteAccount removes the account from the trie. The account is marked as deleted,
+// an account created later at the same address starts with an empty storage.
 func (s *StateDB) DeleteAccount(addr common.Address) bool {
 	stateObject := s.getStateObject(addr)
 	if stateObject == nil {
 		return false
 	}
 	s.deleteStateObject(stateObject)
+	stateObject.deleted = true
+
+	return true
+}
+
+// WipeStorage replaces the storage of the account with an empty one, the balance, the
+// nonce and the code are kept. The account is replaced by a new object (as in
+// CreateAccount), so the change is reverted with the snapshots.
+func (s *StateDB) WipeStorage(addr common.Address) bool {
+	prev := s.getStateObject(addr)
+	if prev == nil {
+		return false
+	}
+	newObj, _ := s.createObject(addr)
+	newObj.created = prev.created
+	newObj.setBalance(prev.data.Balance)
+	newObj.setNonce(prev.data.Nonce)
+	newObj.setCode(common.BytesToHash(prev.CodeHash()), prev.Code(s.Db))
 
 	return true
 }
@@ -512,8 +550,16 @@ func (s *StateDB) deleteStateObject(obj *stateObject) {
 	}
 	// Delete the account from the trie
 	addr := obj.Address()
-	// Get absense proof of account in case the deletion needs the sister node.
-	oracle.PrefetchAccount(big.NewInt(s.Db.BlockNumber.Int64()+1), addr, trie.GenPossibleShortNodePreimage)
+	// Get absense proof of account in case the deletion needs the sister node. There is
+	// no next block at the head of the chain, the sister node is then fetched by its hash
+	// if the deletion needs it (see Oracle.Preimage).
+	if _, err := s.Db.Oracle.PrefetchAccount(big.NewInt(s.Db.BlockNumber.Int64()+1), addr, trie.GenPossibleShortNodePreimage); err != nil {
+		var notFound *oracle.BlockNotFoundError
+		if !errors.As(err, &notFound) {
+			s.setError(fmt.Errorf("deleteStateObject (%x) error: %v", addr[:], err))
+			return
+		}
+	}
 	if err := s.trie.TryDelete(addr[:]); err != nil {
 		s.setError(fmt.Errorf("deleteStateObject (%x) error: %v", addr[:], err))
 	}
@@ -571,7 +617,10 @@ func (s *StateDB) getDeletedStateObject(addr common.Address) *stateObject {
 		if metrics.EnabledExpensive {
 			defer func(start time.Time) { s.AccountReads += time.Since(start) }(time.Now())
 		}
-		oracle.PrefetchAccount(s.Db.BlockNumber, addr, nil)
+		if _, err := s.Db.Oracle.PrefetchAccount(s.Db.BlockNumber, addr, nil); err != nil {
+			s.setError(fmt.Errorf("getDeleteStateObject (%x) error: %v", addr.Bytes(), err))
+			return nil
+		}
 		enc, err := s.trie.TryGet(addr.Bytes())
 		if err != nil {
 			s.setError(fmt.Errorf("getDeleteStateObject (%x) error: %v", addr.Bytes(), err))
@@ -641,6 +690,7 @@ func (s *StateDB) createObject(addr common.Address) (newobj, prev *stateObject)
 		}
 	}
 	newobj = newObject(s, addr, Account{})
+	newobj.created = true
 	if prev == nil {
 		s.journal.append(createObjectChange{account: &addr})
 	} else {
@@ -860,6 +910,7 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 		} else {
 			obj.finalise(true) // Prefetch slots in the background
 		}
+		obj.created = false
 		s.stateObjectsPending[addr] = struct{}{}
 		s.stateObjectsDirty[addr] = struct{}{}
 
@@ -962,10 +1013,12 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (common.Hash, error) {
 
 	for addr := range s.stateObjectsDirty {
 		if obj := s.stateObjects[addr]; !obj.deleted {
-			fmt.Println("dirty state object", addr)
 			// Write any contract code associated with the state object
 			if obj.code != nil && obj.dirtyCode {
-				fmt.Println("write code", common.BytesToHash(obj.CodeHash()))
+				if err := s.Db.Oracle.PutPreimage(common.BytesToHash(obj.CodeHash()), obj.code); err != nil {
+					return common.Hash{}, err
+				}
+				obj.dirtyCode = false
 			}
 			// Write any storage changes in the state object to its storage trie
 			if err := obj.CommitTrie(s.Db); err != nil {
@@ -973,6 +1026,9 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (common.Hash, error) {
 			}
 		}
 	}
+	if len(s.stateObjectsDirty) > 0 {
+		s.stateObjectsDirty = make(map[common.Address]struct{})
+	}
 
 	// Commit objects to the trie, measuring the elapsed time
 	/*codeWriter := s.db.TrieDB().DiskDB().NewBatch()
@@ -1038,7 +1094,6 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (common.Hash, error) {
 		}
 		s.snap, s.snapDestructs, s.snapAccounts, s.snapStorage = nil, nil, nil, nil
 	}*/
-	//fmt.Println("Commit doesn't work!!!")
 	return root, err
 }
 
@@ -1098,6 +1153,12 @@ func (s *StateDB) AddressInAccessList(addr common.Address) bool {
 	return s.accessList.ContainsAddress(addr)
 }
 
+// AccessList returns the addresses and slots in the access list, e.g. the ones accessed
+// by the transaction after its execution.
+func (s *StateDB) AccessList() types.AccessList {
+	return s.accessList.List()
+}
+
 // SlotInAccessList returns true if the given (address, slot)-tuple is in the access list.
 func (s *StateDB) SlotInAccessList(addr common.Address, slot common.Hash) (addressPresent bool, slotPresent bool) {
 	return s.accessList.Contains(addr, slot)
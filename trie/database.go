@@ -7,7 +7,6 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/privacy-scaling-explorations/mpt-witness-generator/oracle"
@@ -29,42 +28,68 @@ func (n rawNode) EncodeRLP(w io.Writer) error {
 type Database struct {
 	BlockNumber *big.Int
 	Root        common.Hash
+	Oracle      oracle.Oracle
 	lock        sync.RWMutex
+
+	err error // the first error of insert, returned by Trie.Commit
 }
 
-func NewDatabase(header types.Header) Database {
-	triedb := Database{BlockNumber: header.Number, Root: header.Root}
+func NewDatabase(header oracle.FullHeader, o oracle.Oracle) (*Database, error) {
+	triedb := &Database{BlockNumber: header.Number, Root: header.Root, Oracle: o}
 	//triedb.preimages = make(map[common.Hash][]byte)
 	//fmt.Println("init database")
-	oracle.PrefetchAccount(header.Number, common.Address{}, nil)
+	if _, err := o.PrefetchAccount(header.Number, common.Address{}, nil); err != nil {
+		return nil, err
+	}
 
 	//panic("preseed")
-	return triedb
+	return triedb, nil
 }
 
-// Node retrieves an encoded cached trie node from memory. If it cannot be found
-// cached, the method queries the persistent database for the content.
+// NewMemoryDatabase returns a database which doesn't need a node: the committed nodes
+// are kept in a MemoryOracle, the state starts empty.
+func NewMemoryDatabase() *Database {
+	return &Database{BlockNumber: new(big.Int), Root: emptyRoot, Oracle: oracle.NewMemoryOracle()}
+}
+
+// Node retrieves an encoded trie node from the oracle, the committed nodes are stored
+// there by insert.
 func (db *Database) Node(hash common.Hash) ([]byte, error) {
-	panic("no Node function")
+	if val := db.Oracle.Preimage(hash); val != nil {
+		return val, nil
+	}
+	return nil, &MissingNodeError{NodeHash: hash}
 }
 
 // node retrieves a cached trie node from memory, or returns nil if none can be
 // found in the memory cache.
 func (db *Database) node(hash common.Hash) Node {
 	//fmt.Println("node", hash)
-	if val := oracle.Preimage(hash); val != nil {
+	if val := db.Oracle.Preimage(hash); val != nil {
 		return mustDecodeNode(hash[:], val)
 	}
 	return nil
 }
 
-// insert inserts a collapsed trie node into the memory database.
-// The blob size must be specified to allow proper size tracking.
-// All nodes inserted by this function will be reference tracked
-// and in theory should only used for **trie nodes** insertion.
+// insert stores the encoding of a committed trie node in the oracle, so that the node
+// can be resolved by a trie opened at the new root later (the node doesn't know it).
 func (db *Database) insert(hash common.Hash, size int, node Node) {
-	// can put things in the oracle here if we care
-	//fmt.Println("insert", hash, size)
+	enc, err := rlp.EncodeToBytes(node)
+	if err == nil {
+		err = db.Oracle.PutPreimage(hash, enc)
+	}
+	if err != nil && db.err == nil {
+		db.err = err
+	}
+}
+
+// commitErr returns (and clears) the first error of insert since the last call.
+func (db *Database) commitErr() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	err := db.err
+	db.err = nil
+	return err
 }
 
 func GenPossibleShortNodePreimage(preimages map[common.Hash][]byte) {
@@ -21,7 +21,6 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
-	"github.com/privacy-scaling-explorations/mpt-witness-generator/oracle"
 )
 
 // SecureTrie wraps a trie with key hashing. In a secure trie, all
@@ -201,7 +200,7 @@ func (t *SecureTrie) NodeIterator(start []byte) NodeIterator {
 // The caller must not hold onto the return value because it will become
 // invalid on the next call to hashKey or secKey.
 func (t *SecureTrie) hashKey(key []byte) []byte {
-	if !oracle.PreventHashingInSecureTrie {
+	if !t.trie.db.Oracle.PreventHashingInSecureTrie() {
 		h := NewHasher(false)
 		h.sha.Reset()
 		h.sha.Write(key)
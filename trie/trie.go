@@ -580,6 +580,9 @@ func (t *Trie) Commit(onleaf LeafCallback) (root common.Hash, err error) {
 	}
 	var newRoot HashNode
 	newRoot, err = h.Commit(t.root, t.db)
+	if err == nil {
+		err = t.db.commitErr()
+	}
 	if onleaf != nil {
 		// The leafch is created in newCommitter if there was an onleaf callback
 		// provided. The commitLoop only _reads_ from it, and the commit
@@ -0,0 +1,139 @@
+package witness
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	destructAddr = common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
+	destructKeys = []common.Hash{common.HexToHash("0x1"), common.HexToHash("0x2"), common.HexToHash("0x3")}
+)
+
+func destructAlloc() core.GenesisAlloc {
+	return core.GenesisAlloc{
+		destructAddr: {
+			Balance: big.NewInt(10),
+			Nonce:   1,
+			Storage: map[common.Hash]common.Hash{destructKeys[0]: common.HexToHash("0x11"), destructKeys[1]: common.HexToHash("0x22")},
+		},
+		common.HexToAddress("0x1"): {Balance: big.NewInt(1)},
+		common.HexToAddress("0x3"): {Balance: big.NewInt(3)},
+	}
+}
+
+// proofTypes returns the proof types of the modifications in the chained witness.
+func proofTypes(nodes []Node) []string {
+	var types []string
+	for _, node := range nodes {
+		if node.Start != nil && node.Start.ProofType != "Disabled" {
+			types = append(types, node.Start.ProofType)
+		}
+	}
+	return types
+}
+
+func TestDestructAndResurrect(t *testing.T) {
+	statedb := offlineStateDB(t, destructAlloc())
+
+	// The account with storage is destructed, then re-created at the same address with
+	// another storage, which must not contain the slots of the destructed account.
+	trieModifications := []TrieModification{
+		{Type: StorageWiped, Address: destructAddr},
+		{Type: AccountDestructed, Address: destructAddr},
+		{Type: AccountCreate, Address: destructAddr},
+		{Type: BalanceChanged, Address: destructAddr, Balance: big.NewInt(5)},
+		{Type: StorageChanged, Address: destructAddr, Key: destructKeys[2], Value: common.HexToHash("0x33")},
+	}
+	nodes, err := obtainTwoProofsAndConvertToWitness(trieModifications, statedb, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := proofTypes(nodes)
+	if len(types) != len(trieModifications) || types[0] != "StorageWiped" || types[1] != "AccountDestructed" {
+		t.Fatalf("unexpected proof types %v", types)
+	}
+
+	alloc := destructAlloc()
+	alloc[destructAddr] = core.GenesisAccount{
+		Balance: big.NewInt(5),
+		Storage: map[common.Hash]common.Hash{destructKeys[2]: common.HexToHash("0x33")},
+	}
+	if statedb.IntermediateRoot(false) != offlineStateDB(t, alloc).IntermediateRoot(false) {
+		t.Fatal("the state root after the resurrection differs from the state with the new account")
+	}
+	if v := statedb.GetState(destructAddr, destructKeys[0]); v != (common.Hash{}) {
+		t.Fatalf("the slot of the destructed account has the value %s", v.Hex())
+	}
+}
+
+func TestDestructModifications(t *testing.T) {
+	statedb := offlineStateDB(t, destructAlloc())
+	start := statedb.IntermediateRoot(false)
+	created := common.HexToAddress("0xc")
+
+	// Under EIP-6780 only the account created in the transaction self-destructs.
+	statedb.CreateAccount(created)
+	statedb.SetState(created, destructKeys[0], common.HexToHash("0x1"))
+	if !statedb.Selfdestruct6780(created) {
+		t.Fatal("the account created in the transaction didn't self-destruct")
+	}
+	if statedb.Selfdestruct6780(destructAddr) {
+		t.Fatal("an existing account self-destructed")
+	}
+	if trieModifications := ModificationsFromJournal(statedb); len(trieModifications) != 0 {
+		t.Fatalf("unexpected modifications %+v", trieModifications)
+	}
+	statedb.IntermediateRoot(false)
+
+	// Before EIP-6780 any account self-destructs, the wipe of its storage precedes it.
+	statedb.Suicide(destructAddr)
+	destructed := ModificationsFromJournal(statedb)
+	expected := []TrieModification{
+		{Type: StorageWiped, Address: destructAddr},
+		{Type: AccountDestructed, Address: destructAddr},
+	}
+	if !reflect.DeepEqual(destructed, expected) {
+		t.Fatalf("modifications %+v, expected %+v", destructed, expected)
+	}
+	destructedRoot, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// In the next transaction the account is re-created with a storage slot.
+	statedb.CreateAccount(destructAddr)
+	statedb.SetState(destructAddr, destructKeys[2], common.HexToHash("0x33"))
+	recreated := ModificationsFromJournal(statedb)
+	expected = []TrieModification{
+		{Type: AccountCreate, Address: destructAddr},
+		{Type: StorageChanged, Address: destructAddr, Key: destructKeys[2], Value: common.HexToHash("0x33")},
+	}
+	if !reflect.DeepEqual(recreated, expected) {
+		t.Fatalf("modifications %+v, expected %+v", recreated, expected)
+	}
+	end := statedb.IntermediateRoot(false)
+
+	fresh := offlineStateDB(t, destructAlloc())
+	if _, err := obtainTwoProofsAndConvertToWitness(append(destructed, recreated...), fresh, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if fresh.IntermediateRoot(false) != end {
+		t.Fatal("the state root after the witness differs from the modified state")
+	}
+
+	// The difference of the tries has the wipe too.
+	preimages := map[common.Hash][]byte{crypto.Keccak256Hash(destructAddr[:]): destructAddr[:]}
+	trieModifications, err := ModificationsBetween(statedb.Db, start, destructedRoot, preimages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trieModifications) != 2 || trieModifications[0].Type != StorageWiped || trieModifications[1].Type != AccountDestructed {
+		t.Fatalf("unexpected modifications %+v", trieModifications)
+	}
+}
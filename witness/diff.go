@@ -0,0 +1,192 @@
+package witness
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/state"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/trie"
+)
+
+// ModificationsBetween returns the modifications which take the state with root rootA to
+// the state with root rootB, sorted by the hashed address and the hashed storage key (the
+// order of the tries). Only the subtries which differ are walked: the account tries with
+// the difference iterator, and the storage tries of the accounts whose storage root changed.
+//
+// The tries know only the hashed keys, they are set in AddressHash and KeyHash. Address
+// and Key are looked up in preimages (e.g. StateDB.Preimages), they are zero when the
+// preimage is unknown. The witness can't be generated for such modifications, GetWitness
+// rejects them with a *MissingPreimageError.
+func ModificationsBetween(db state.Database, rootA, rootB common.Hash, preimages map[common.Hash][]byte) ([]TrieModification, error) {
+	trieA, err := db.OpenTrie(rootA)
+	if err != nil {
+		return nil, err
+	}
+	trieB, err := db.OpenTrie(rootB)
+	if err != nil {
+		return nil, err
+	}
+	removed, added, err := leafDiff(trieA, trieB)
+	if err != nil {
+		return nil, err
+	}
+
+	preimage := func(hash common.Hash) []byte {
+		if db.Oracle.PreventHashingInSecureTrie() {
+			// The keys are not hashed.
+			return hash[:]
+		}
+		if key := preimages[hash]; crypto.Keccak256Hash(key) == hash {
+			return key
+		}
+		return nil
+	}
+
+	var trieModifications []TrieModification
+	for _, addrHash := range sortedKeys(removed, added) {
+		addr := common.BytesToAddress(preimage(addrHash))
+		newMod := func(t ProofType) TrieModification {
+			return TrieModification{Type: t, Address: addr, AddressHash: addrHash}
+		}
+		encA, encB := removed[addrHash], added[addrHash]
+		var pre, post state.Account
+		if encA == nil {
+			trieModifications = append(trieModifications, newMod(AccountCreate))
+			// The changes are relative to the empty account.
+			pre = state.Account{Balance: new(big.Int), Root: emptyRoot, CodeHash: emptyCodeHash}
+		} else if err := rlp.DecodeBytes(encA, &pre); err != nil {
+			return nil, err
+		}
+		if encB == nil {
+			if pre.Root != emptyRoot {
+				trieModifications = append(trieModifications, newMod(StorageWiped))
+			}
+			trieModifications = append(trieModifications, newMod(AccountDestructed))
+			continue
+		}
+		if err := rlp.DecodeBytes(encB, &post); err != nil {
+			return nil, err
+		}
+
+		if pre.Nonce != post.Nonce {
+			mod := newMod(NonceChanged)
+			mod.Nonce = post.Nonce
+			trieModifications = append(trieModifications, mod)
+		}
+		if pre.Balance.Cmp(post.Balance) != 0 {
+			mod := newMod(BalanceChanged)
+			mod.Balance = post.Balance
+			trieModifications = append(trieModifications, mod)
+		}
+		if !bytes.Equal(pre.CodeHash, post.CodeHash) {
+			code, err := db.ContractCode(addrHash, common.BytesToHash(post.CodeHash))
+			if err != nil {
+				return nil, err
+			}
+			mod := newMod(CodeHashChanged)
+			mod.CodeHash = code
+			trieModifications = append(trieModifications, mod)
+		}
+
+		if pre.Root == post.Root {
+			continue
+		}
+		storageA, err := db.OpenStorageTrie(addrHash, pre.Root)
+		if err != nil {
+			return nil, err
+		}
+		storageB, err := db.OpenStorageTrie(addrHash, post.Root)
+		if err != nil {
+			return nil, err
+		}
+		removedSlots, addedSlots, err := leafDiff(storageA, storageB)
+		if err != nil {
+			return nil, err
+		}
+		for _, keyHash := range sortedKeys(removedSlots, addedSlots) {
+			mod := newMod(StorageChanged)
+			mod.Key = common.BytesToHash(preimage(keyHash))
+			mod.KeyHash = keyHash
+			// A removed slot without the new value is deleted, its value stays zero.
+			if enc := addedSlots[keyHash]; enc != nil {
+				_, content, _, err := rlp.Split(enc)
+				if err != nil {
+					return nil, err
+				}
+				mod.Value = common.BytesToHash(content)
+			}
+			trieModifications = append(trieModifications, mod)
+		}
+	}
+	return trieModifications, nil
+}
+
+// MissingPreimageError is returned for a modification with AddressHash or KeyHash whose
+// address or storage key isn't known (see ModificationsBetween).
+type MissingPreimageError struct {
+	Hash common.Hash
+}
+
+func (e *MissingPreimageError) Error() string {
+	return fmt.Sprintf("missing preimage of %s", e.Hash)
+}
+
+// checkPreimages returns a *MissingPreimageError if Address or Key is not the preimage of
+// AddressHash or KeyHash (when they are set), the witness would be for another key.
+func (tMod *TrieModification) checkPreimages() error {
+	if tMod.AddressHash != (common.Hash{}) && crypto.Keccak256Hash(tMod.Address[:]) != tMod.AddressHash {
+		return &MissingPreimageError{Hash: tMod.AddressHash}
+	}
+	if tMod.KeyHash != (common.Hash{}) && crypto.Keccak256Hash(tMod.Key[:]) != tMod.KeyHash {
+		return &MissingPreimageError{Hash: tMod.KeyHash}
+	}
+	return nil
+}
+
+var (
+	emptyRoot     = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+	emptyCodeHash = crypto.Keccak256(nil)
+)
+
+// leafDiff returns the leaves which are in a but not in b (removed) and the leaves which
+// are in b but not in a (added) by their keys, a changed leaf is in both.
+func leafDiff(a, b state.Trie) (removed, added map[common.Hash][]byte, err error) {
+	if removed, err = leaves(b, a); err != nil {
+		return nil, nil, err
+	}
+	if added, err = leaves(a, b); err != nil {
+		return nil, nil, err
+	}
+	return removed, added, nil
+}
+
+// leaves returns the leaves of b which are not in a.
+func leaves(a, b state.Trie) (map[common.Hash][]byte, error) {
+	diff, _ := trie.NewDifferenceIterator(a.NodeIterator(nil), b.NodeIterator(nil))
+	it := trie.NewIterator(diff)
+	leaves := make(map[common.Hash][]byte)
+	for it.Next() {
+		leaves[common.BytesToHash(it.Key)] = common.CopyBytes(it.Value)
+	}
+	return leaves, it.Err
+}
+
+// sortedKeys returns the keys of both maps in the order of the trie.
+func sortedKeys(a, b map[common.Hash][]byte) []common.Hash {
+	var keys []common.Hash
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+	return keys
+}
@@ -0,0 +1,152 @@
+package witness
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestModificationsBetween(t *testing.T) {
+	a, b, c, d := common.HexToAddress("0xa"), common.HexToAddress("0xb"), common.HexToAddress("0xc"), common.HexToAddress("0xd")
+	k1, k2, k3, k4 := common.HexToHash("0x1"), common.HexToHash("0x2"), common.HexToHash("0x3"), common.HexToHash("0x4")
+	alloc := core.GenesisAlloc{
+		a: {Balance: big.NewInt(1), Storage: map[common.Hash]common.Hash{k1: common.HexToHash("0x11"), k2: common.HexToHash("0x22"), k3: common.HexToHash("0x33")}},
+		b: {Balance: big.NewInt(2)},
+		c: {Balance: big.NewInt(3)},
+	}
+	statedb := offlineStateDB(t, alloc)
+	rootA := statedb.IntermediateRoot(false)
+
+	statedb.SetState(a, k1, common.HexToHash("0x12"))
+	statedb.SetState(a, k2, common.Hash{})
+	statedb.SetState(a, k4, common.HexToHash("0x44"))
+	statedb.SetNonce(a, 1)
+	statedb.DeleteAccount(b)
+	statedb.CreateAccount(d)
+	statedb.SetBalance(d, big.NewInt(4))
+	statedb.SetCode(d, []byte{0x60, 0x01})
+	statedb.SetState(d, k1, common.HexToHash("0x1d"))
+	rootB, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	preimages := make(map[common.Hash][]byte)
+	for _, addr := range []common.Address{a, b, c, d} {
+		preimages[crypto.Keccak256Hash(addr[:])] = common.CopyBytes(addr[:])
+	}
+	for _, key := range []common.Hash{k1, k2, k3, k4} {
+		preimages[crypto.Keccak256Hash(key[:])] = common.CopyBytes(key[:])
+	}
+	trieModifications, err := ModificationsBetween(statedb.Db, rootA, rootB, preimages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type change struct {
+		Type ProofType
+		Key  common.Hash
+	}
+	expected := map[common.Address][]change{
+		a: {{NonceChanged, common.Hash{}}, {StorageChanged, k1}, {StorageChanged, k2}, {StorageChanged, k4}},
+		b: {{AccountDestructed, common.Hash{}}},
+		d: {{AccountCreate, common.Hash{}}, {BalanceChanged, common.Hash{}}, {CodeHashChanged, common.Hash{}}, {StorageChanged, k1}},
+	}
+	var addrs []common.Address
+	for addr := range expected {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(crypto.Keccak256(addrs[i][:]), crypto.Keccak256(addrs[j][:])) < 0
+	})
+	var i int
+	for _, addr := range addrs {
+		changes := expected[addr]
+		// The storage changes are in the order of the hashed keys.
+		sort.SliceStable(changes, func(i, j int) bool {
+			if changes[i].Type != StorageChanged || changes[j].Type != StorageChanged {
+				return false
+			}
+			return bytes.Compare(crypto.Keccak256(changes[i].Key[:]), crypto.Keccak256(changes[j].Key[:])) < 0
+		})
+		for _, ch := range changes {
+			if i >= len(trieModifications) {
+				t.Fatalf("missing modification %v of %s", ch, addr)
+			}
+			mod := trieModifications[i]
+			if mod.Type != ch.Type || mod.Address != addr || mod.Key != ch.Key || mod.AddressHash != crypto.Keccak256Hash(addr[:]) {
+				t.Fatalf("modification %d is %+v, expected %v of %s", i, mod, ch, addr)
+			}
+			i++
+		}
+	}
+	if i != len(trieModifications) {
+		t.Fatalf("unexpected modifications %+v", trieModifications[i:])
+	}
+
+	// The modifications take a fresh copy of state A to state B.
+	fresh := offlineStateDB(t, alloc)
+	if _, err := obtainTwoProofsAndConvertToWitness(trieModifications, fresh, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if root := fresh.IntermediateRoot(false); root != rootB {
+		t.Fatalf("state root %s after the modifications, expected %s", root, rootB)
+	}
+}
+
+func TestModificationsBetweenUnknownPreimages(t *testing.T) {
+	addr, key := common.HexToAddress("0xa"), common.HexToHash("0x1")
+	alloc := core.GenesisAlloc{addr: {Balance: big.NewInt(1)}}
+	statedb := offlineStateDB(t, alloc)
+	rootA := statedb.IntermediateRoot(false)
+	statedb.SetState(addr, key, common.HexToHash("0x11"))
+	rootB, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the preimages the modifications are on the hashed keys only, the witness
+	// can't be generated for them.
+	addrHash, keyHash := crypto.Keccak256Hash(addr[:]), crypto.Keccak256Hash(key[:])
+	for _, test := range []struct {
+		preimages map[common.Hash][]byte
+		addr      common.Address
+		key       common.Hash
+		missing   common.Hash
+	}{
+		{nil, common.Address{}, common.Hash{}, addrHash},
+		{map[common.Hash][]byte{addrHash: addr[:]}, addr, common.Hash{}, keyHash},
+		{map[common.Hash][]byte{addrHash: addr[:], keyHash: key[:]}, addr, key, common.Hash{}},
+	} {
+		trieModifications, err := ModificationsBetween(statedb.Db, rootA, rootB, test.preimages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(trieModifications) != 1 {
+			t.Fatalf("expected a single modification, got %+v", trieModifications)
+		}
+		mod := trieModifications[0]
+		if mod.Type != StorageChanged || mod.Address != test.addr || mod.Key != test.key ||
+			mod.AddressHash != addrHash || mod.KeyHash != keyHash || mod.Value != common.HexToHash("0x11") {
+			t.Fatalf("unexpected modification %+v", mod)
+		}
+
+		_, err = obtainTwoProofsAndConvertToWitness(trieModifications, offlineStateDB(t, alloc), 0, false)
+		if test.missing == (common.Hash{}) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		var me *MissingPreimageError
+		if !errors.As(err, &me) || me.Hash != test.missing {
+			t.Fatalf("expected a MissingPreimageError for %s, got %v", test.missing, err)
+		}
+	}
+}
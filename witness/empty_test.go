@@ -0,0 +1,116 @@
+package witness
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+)
+
+func TestDeleteEmptyObjects(t *testing.T) {
+	emptied := common.HexToAddress("0x4")
+	alloc := func(account *core.GenesisAccount) core.GenesisAlloc {
+		alloc := core.GenesisAlloc{
+			common.HexToAddress("0x1"): {Balance: big.NewInt(1)},
+			common.HexToAddress("0x3"): {Balance: big.NewInt(3)},
+		}
+		if account != nil {
+			alloc[emptied] = *account
+		}
+		return alloc
+	}
+	trieModifications := []TrieModification{
+		{Type: BalanceChanged, Address: emptied, Balance: new(big.Int)},
+	}
+
+	for _, test := range []struct {
+		deleteEmptyObjects bool
+		proofTypes         []string
+		alloc              core.GenesisAlloc
+	}{
+		// The empty account stays in the trie.
+		{false, []string{"BalanceChanged"}, alloc(&core.GenesisAccount{Balance: new(big.Int)})},
+		// The empty account is removed after the modification.
+		{true, []string{"BalanceChanged", "AccountDestructed"}, alloc(nil)},
+	} {
+		statedb := offlineStateDB(t, alloc(&core.GenesisAccount{Balance: big.NewInt(5)}))
+		nodes, err := obtainTwoProofsAndConvertToWitness(trieModifications, statedb, 0, test.deleteEmptyObjects)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if types := proofTypes(nodes); !reflect.DeepEqual(types, test.proofTypes) {
+			t.Fatalf("deleteEmptyObjects %v: proof types %v, expected %v", test.deleteEmptyObjects, types, test.proofTypes)
+		}
+		if statedb.IntermediateRoot(false) != offlineStateDB(t, test.alloc).IntermediateRoot(false) {
+			t.Fatalf("deleteEmptyObjects %v: unexpected state root", test.deleteEmptyObjects)
+		}
+	}
+}
+
+func TestDeleteEmptyObjectsDestructed(t *testing.T) {
+	// An empty account with storage, e.g. a contract without nonce and balance which
+	// self-destructs: the account is destructed once, by the explicit modification.
+	alloc := destructAlloc()
+	alloc[destructAddr] = core.GenesisAccount{
+		Balance: new(big.Int),
+		Storage: map[common.Hash]common.Hash{destructKeys[0]: common.HexToHash("0x11")},
+	}
+	statedb := offlineStateDB(t, alloc)
+	start := statedb.IntermediateRoot(false)
+	trieModifications := []TrieModification{
+		{Type: StorageWiped, Address: destructAddr},
+		{Type: AccountDestructed, Address: destructAddr},
+	}
+	nodes, err := obtainTwoProofsAndConvertToWitness(trieModifications, statedb, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types := proofTypes(nodes); !reflect.DeepEqual(types, []string{"StorageWiped", "AccountDestructed"}) {
+		t.Fatalf("proof types %v, expected [StorageWiped AccountDestructed]", types)
+	}
+	delete(alloc, destructAddr)
+	expected := offlineStateDB(t, alloc).IntermediateRoot(false)
+	if sRoot, cRoot := witnessRoots(nodes); sRoot != start || cRoot != expected {
+		t.Fatalf("witness from %s to %s, expected from %s to %s", sRoot, cRoot, start, expected)
+	}
+}
+
+func TestDeleteEmptyObjectsRecreated(t *testing.T) {
+	// The account is emptied (and removed) by a transaction, created again with a slot
+	// by the next one and destructed by the last one: only the destruction of the last
+	// transaction waits for the explicit modifications.
+	addr := common.HexToAddress("0x4")
+	alloc := destructAlloc()
+	alloc[addr] = core.GenesisAccount{Balance: big.NewInt(5)}
+	statedb := offlineStateDB(t, alloc)
+	trieModifications := []TrieModification{
+		{Type: BalanceChanged, Address: addr, Balance: new(big.Int)},
+		{Type: AccountCreate, Address: addr},
+		{Type: StorageChanged, Address: addr, Key: destructKeys[0], Value: common.HexToHash("0x11")},
+		{Type: StorageWiped, Address: addr},
+		{Type: AccountDestructed, Address: addr},
+	}
+	nodes, err := obtainTwoProofsAndConvertToWitness(trieModifications, statedb, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedTypes := []string{"BalanceChanged", "AccountDestructed", "NonceChanged", "StorageChanged", "StorageWiped", "AccountDestructed"}
+	if types := proofTypes(nodes); !reflect.DeepEqual(types, expectedTypes) {
+		t.Fatalf("proof types %v, expected %v", types, expectedTypes)
+	}
+
+	// The roots after the removal by the first transaction and at the end.
+	var roots []common.Hash
+	for _, node := range nodes {
+		if node.Start != nil && node.Start.ProofType != "Disabled" {
+			roots = append(roots, common.BytesToHash(node.Values[1][1:33]))
+		}
+	}
+	delete(alloc, addr)
+	expected := offlineStateDB(t, alloc).IntermediateRoot(false)
+	if roots[1] != expected || roots[len(roots)-1] != expected {
+		t.Fatalf("roots %v, expected %s after the removal and at the end", roots, expected)
+	}
+}
@@ -0,0 +1,203 @@
+package witness
+
+import (
+	"math/big"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/devnode"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/oracle"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/state"
+)
+
+// The tests in this file use an in-process node with the state given by the genesis
+// alloc instead of `geth --dev`, so the trie shapes are exactly the ones needed.
+
+func devnodeStateDB(t *testing.T, alloc core.GenesisAlloc) *state.StateDB {
+	node, err := devnode.New(alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(node)
+	t.Cleanup(server.Close)
+
+	o := oracle.NewRPCOracle(server.URL)
+	o.CacheMode = oracle.Passthrough
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(big.NewInt(0)), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statedb
+}
+
+func TestDevnodeNonExistingAccountSingleLeafTrie(t *testing.T) {
+	// Only one element in the trie - the account with "wrong" address.
+	statedb := devnodeStateDB(t, core.GenesisAlloc{
+		common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9"): {Balance: big.NewInt(1)},
+	})
+
+	trieMod := TrieModification{
+		Type:    AccountDoesNotExist,
+		Address: common.HexToAddress("0x10"),
+	}
+	nodes, err := obtainTwoProofsAndConvertToWitness([]TrieModification{trieMod}, statedb, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// start, account leaf, end
+	if len(nodes) != 3 || nodes[1].Account == nil {
+		t.Fatalf("expected the wrong leaf as the only account node, got %d nodes", len(nodes))
+	}
+}
+
+func TestDevnodeStorageChangedAccountInFirstLevel(t *testing.T) {
+	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
+	// Two accounts: the root is a branch and both accounts are in the first level.
+	statedb := devnodeStateDB(t, core.GenesisAlloc{
+		addr: {
+			Balance: big.NewInt(1),
+			Storage: map[common.Hash]common.Hash{
+				common.HexToHash("0x1"): common.HexToHash("0x11"),
+				common.HexToHash("0x2"): common.HexToHash("0x22"),
+			},
+		},
+		common.HexToAddress("0x1"): {Balance: big.NewInt(1)},
+	})
+
+	// GetState calls GetCommittedState which calls PrefetchStorage to get the preimages.
+	statedb.GetState(addr, common.HexToHash("0x1"))
+
+	trieMod := TrieModification{
+		Type:    StorageChanged,
+		Key:     common.HexToHash("0x1"),
+		Value:   common.HexToHash("0x17"),
+		Address: addr,
+	}
+	nodes, err := obtainTwoProofsAndConvertToWitness([]TrieModification{trieMod}, statedb, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// start, account branch, account leaf, storage branch, storage leaf, end
+	if len(nodes) != 6 {
+		t.Fatalf("expected 6 nodes, got %d", len(nodes))
+	}
+	if nodes[0].Start.ProofType != "StorageChanged" || nodes[2].Account == nil || nodes[4].Storage == nil {
+		t.Fatal("unexpected witness nodes")
+	}
+}
+
+func TestDevnodeWitnessAfterCommit(t *testing.T) {
+	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
+	key1, key2 := common.HexToHash("0x1"), common.HexToHash("0x2")
+	statedb := devnodeStateDB(t, core.GenesisAlloc{
+		addr:                       {Balance: big.NewInt(1), Storage: map[common.Hash]common.Hash{key1: common.HexToHash("0x11")}},
+		common.HexToAddress("0x1"): {Balance: big.NewInt(1)},
+	})
+
+	first := []TrieModification{
+		{Type: StorageChanged, Address: addr, Key: key2, Value: common.HexToHash("0x22")},
+		{Type: BalanceChanged, Address: addr, Balance: big.NewInt(5)},
+	}
+	if _, err := obtainTwoProofsAndConvertToWitness(first, statedb, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The committed nodes are in the oracle, the node only knows the genesis state.
+	reopened, err := state.New(root, statedb.Db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := []TrieModification{
+		{Type: StorageChanged, Address: addr, Key: key2, Value: common.HexToHash("0x23")},
+		{Type: NonceChanged, Address: addr, Nonce: 1},
+	}
+	if _, err := obtainTwoProofsAndConvertToWitness(second, reopened, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := state.NewFromAlloc(core.GenesisAlloc{
+		addr: {
+			Balance: big.NewInt(5),
+			Nonce:   1,
+			Storage: map[common.Hash]common.Hash{key1: common.HexToHash("0x11"), key2: common.HexToHash("0x23")},
+		},
+		common.HexToAddress("0x1"): {Balance: big.NewInt(1)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.IntermediateRoot(false) != expected.IntermediateRoot(false) {
+		t.Fatal("the state root after both witnesses differs from the expected state")
+	}
+}
+
+func TestDevnodeGetWitnessDestruct(t *testing.T) {
+	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
+	alloc := core.GenesisAlloc{
+		addr: {
+			Balance: big.NewInt(5),
+			Storage: map[common.Hash]common.Hash{common.HexToHash("0x1"): common.HexToHash("0x11")},
+		},
+		common.HexToAddress("0x1"): {Balance: big.NewInt(1)},
+		common.HexToAddress("0x3"): {Balance: big.NewInt(3)},
+	}
+	node, err := devnode.New(alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	// The node has the genesis block only, there is no next block with the proof of the
+	// deleted account.
+	expected := offlineStateDB(t, core.GenesisAlloc{
+		common.HexToAddress("0x1"): {Balance: big.NewInt(1)},
+		common.HexToAddress("0x3"): {Balance: big.NewInt(3)},
+	}).IntermediateRoot(false)
+	for _, test := range []struct {
+		trieModifications  []TrieModification
+		deleteEmptyObjects bool
+		proofTypes         []string
+	}{
+		{
+			[]TrieModification{{Type: StorageWiped, Address: addr}, {Type: AccountDestructed, Address: addr}},
+			false,
+			[]string{"StorageWiped", "AccountDestructed"},
+		},
+		{
+			[]TrieModification{{Type: StorageWiped, Address: addr}, {Type: BalanceChanged, Address: addr, Balance: new(big.Int)}},
+			true,
+			[]string{"StorageWiped", "BalanceChanged", "AccountDestructed"},
+		},
+	} {
+		nodes, err := GetWitness(server.URL, oracle.BlockNumberRef(big.NewInt(0)), test.trieModifications, WithDeleteEmptyObjects(test.deleteEmptyObjects))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if types := proofTypes(nodes); !reflect.DeepEqual(types, test.proofTypes) {
+			t.Fatalf("proof types %v, expected %v", types, test.proofTypes)
+		}
+		sRoot, cRoot := witnessRoots(nodes)
+		if sRoot != node.Root() || cRoot != expected {
+			t.Fatalf("witness from %s to %s, expected from %s to %s", sRoot, cRoot, node.Root(), expected)
+		}
+	}
+}
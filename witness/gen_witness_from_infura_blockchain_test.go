@@ -1123,9 +1123,13 @@ func TestExtensionInFirstStorageLevel(t *testing.T) {
 func TestExtensionInFirstStorageLevelOneKeyByte(t *testing.T) {
 	blockNum := 13284469
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
@@ -1156,9 +1160,13 @@ func TestExtensionInFirstStorageLevelOneKeyByte(t *testing.T) {
 func TestExtensionAddedInFirstStorageLevelOneKeyByte(t *testing.T) {
 	blockNum := 13284469
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
@@ -1186,9 +1194,13 @@ func TestExtensionAddedInFirstStorageLevelOneKeyByte(t *testing.T) {
 func TestExtensionInFirstStorageLevelTwoKeyBytes(t *testing.T) {
 	blockNum := 13284469
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
@@ -1217,9 +1229,13 @@ func TestExtensionInFirstStorageLevelTwoKeyBytes(t *testing.T) {
 func TestExtensionAddedInFirstStorageLevelTwoKeyBytes(t *testing.T) {
 	blockNum := 13284469
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
@@ -1248,9 +1264,13 @@ func TestExtensionAddedInFirstStorageLevelTwoKeyBytes(t *testing.T) {
 func TestExtensionThreeKeyBytesSel2(t *testing.T) {
 	blockNum := 13284469
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50feb1f2580138bc623c97557286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
@@ -1281,9 +1301,13 @@ func TestExtensionThreeKeyBytesSel2(t *testing.T) {
 func TestExtensionAddedThreeKeyBytesSel2(t *testing.T) {
 	blockNum := 13284469
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50feb1f2580138bc623c97557286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
@@ -1312,9 +1336,13 @@ func TestExtensionAddedThreeKeyBytesSel2(t *testing.T) {
 func TestExtensionDeletedThreeKeyBytesSel2(t *testing.T) {
 	blockNum := 13284469
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50feb1f2580138bc623c97557286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
@@ -1345,9 +1373,13 @@ func TestExtensionDeletedThreeKeyBytesSel2(t *testing.T) {
 func TestExtensionThreeKeyBytes(t *testing.T) {
 	blockNum := 13284469
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50fbe1f25aa0843b623c97557286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
@@ -1388,9 +1420,13 @@ func TestExtensionThreeKeyBytes(t *testing.T) {
 func TestOnlyLeafInStorageProof(t *testing.T) {
 	blockNum := 14209217
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	statedb.DisableLoadingRemoteAccounts()
 	
@@ -1427,9 +1463,13 @@ func TestOnlyLeafInStorageProof(t *testing.T) {
 func TestStorageLeafInFirstLevelAfterPlaceholder(t *testing.T) {
 	blockNum := 14209217
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	statedb.DisableLoadingRemoteAccounts()
 	
@@ -1469,9 +1509,13 @@ func TestStorageLeafInFirstLevelAfterPlaceholder(t *testing.T) {
 func TestLeafAddedToEmptyTrie(t *testing.T) {
 	blockNum := 14209217
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	statedb.DisableLoadingRemoteAccounts()
 	
@@ -1511,9 +1555,13 @@ func TestLeafAddedToEmptyTrie(t *testing.T) {
 func TestDeleteToEmptyTrie(t *testing.T) {
 	blockNum := 14209217
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	statedb.DisableLoadingRemoteAccounts()
 	
@@ -1583,9 +1631,13 @@ func TestUpdateTwoModifications(t *testing.T) {
 func TestNonceModCShort(t *testing.T) {
 	blockNum := 14766377
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x68D5a6E78BD8734B7d190cbD98549B72bFa0800B")
 
 	trieMod := TrieModification{
@@ -1601,9 +1653,13 @@ func TestNonceModCShort(t *testing.T) {
 func TestNonceModCLong(t *testing.T) {
 	blockNum := 14766377
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x68D5a6E78BD8734B7d190cbD98549B72bFa0800B")
 
 	trieMod := TrieModification{
@@ -1619,9 +1675,13 @@ func TestNonceModCLong(t *testing.T) {
 func TestBalanceModCShort(t *testing.T) {
 	blockNum := 14766377
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x68D5a6E78BD8734B7d190cbD98549B72bFa0800B")
 
 	trieMod := TrieModification{
@@ -1637,9 +1697,13 @@ func TestBalanceModCShort(t *testing.T) {
 func TestBalanceModCLong(t *testing.T) {
 	blockNum := 14766377
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x68D5a6E78BD8734B7d190cbD98549B72bFa0800B")
 
 	trieMod := TrieModification{
@@ -1655,9 +1719,13 @@ func TestBalanceModCLong(t *testing.T) {
 func TestAddAccount(t *testing.T) {
 	blockNum := 1
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	
 	addr := common.HexToAddress("0xaaaccf12580138bc2bbceeeaa111df4e42ab81ab")
 	statedb.IntermediateRoot(false)
@@ -1674,9 +1742,13 @@ func TestAddAccount(t *testing.T) {
 func TestDeleteAccount(t *testing.T) {
 	blockNum := 1
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	
 	addr := common.HexToAddress("0xaaaccf12580138bc2bbceeeaa111df4e42ab81ab")
 	statedb.CreateAccount(addr)
@@ -1698,9 +1770,13 @@ func TestImplicitlyCreateAccountWithNonce(t *testing.T) {
 	// children at `modified_node` is nil.
 	blockNum := 1
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	
 	addr := common.HexToAddress("0xaabccf12580138bc2bbceeeaa111df4e42ab81ab")
 
@@ -1717,9 +1793,13 @@ func TestImplicitlyCreateAccountWithNonce(t *testing.T) {
 func TestImplicitlyCreateAccountWithBalance(t *testing.T) {
 	blockNum := 1
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	
 	addr := common.HexToAddress("0xaabccf12580138bc2bbceeeaa111df4e42ab81ab")
 
@@ -1736,9 +1816,13 @@ func TestImplicitlyCreateAccountWithBalance(t *testing.T) {
 func TestImplicitlyCreateAccountWithCodeHash(t *testing.T) {
 	blockNum := 1
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	
 	addr := common.HexToAddress("0xaabccf12580138bc2bbceeeaa111df4e42ab81ab")
 	codeHash := []byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
@@ -1756,9 +1840,13 @@ func TestImplicitlyCreateAccountWithCodeHash(t *testing.T) {
 func TestAccountAddPlaceholderBranch(t *testing.T) {
 	blockNum := 13284469
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	
 	// We need an account that doesn't exist yet.
 	i := 21
@@ -1778,9 +1866,13 @@ func TestAccountAddPlaceholderBranch(t *testing.T) {
 func TestAccountDeletePlaceholderBranch(t *testing.T) {
 	blockNum := 13284469
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	
 	i := 21
 	h := fmt.Sprintf("0x%d", i)
@@ -1800,9 +1892,13 @@ func TestAccountDeletePlaceholderBranch(t *testing.T) {
 func TestAccountAddPlaceholderExtension(t *testing.T) {
 	blockNum := 13284469
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	
 	// We need an account that doesn't exist yet.
 	i := 40
@@ -1822,9 +1918,13 @@ func TestAccountAddPlaceholderExtension(t *testing.T) {
 func TestAccountDeletePlaceholderExtension(t *testing.T) {
 	blockNum := 13284469
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	
 	i := 40
 	h := fmt.Sprintf("0x%d", i)
@@ -1846,9 +1946,13 @@ func TestNonExistingAccountNilObject(t *testing.T) {
 	// At the account address, there is a nil object.
 	blockNum := 1
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	
 	addr := common.HexToAddress("0xaaaccf12580138bc2bbceeeaa111df4e42ab81ab")
 	statedb.IntermediateRoot(false)
@@ -1869,9 +1973,13 @@ func TestNonExistingAccount(t *testing.T) {
 	// to the position in branch.
 	blockNum := 13284469
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	i := 21
 	h := fmt.Sprintf("0x%d", i)
@@ -1891,9 +1999,13 @@ func TestNonExistingAccount(t *testing.T) {
 func TestAccountBranchPlaceholderDeeper(t *testing.T) {
 	blockNum := 13284469
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	h := fmt.Sprintf("0xa21%d", 0)
 	addr := common.HexToAddress(h)
@@ -1939,16 +2051,20 @@ func TestLeafInLastLevel(t *testing.T) {
 	*/
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
 	
 	statedb.CreateAccount(addr)
 
-	oracle.PreventHashingInSecureTrie = true // to store the unchanged key
+	o.PreventHashing = true // to store the unchanged key
 
 	key1 := common.HexToHash("0x1")
 	val1 := common.BigToHash(big.NewInt(int64(1)))
@@ -1979,23 +2095,25 @@ func TestLeafInLastLevel(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness("LeafInLastLevel", trieModifications, statedb)
-
-	oracle.PreventHashingInSecureTrie = false
 }
 
 func TestLeafWithOneNibble(t *testing.T) {
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
 	
 	statedb.CreateAccount(addr)
 
-	oracle.PreventHashingInSecureTrie = true // to store the unchanged key
+	o.PreventHashing = true // to store the unchanged key
 
 	key1 := common.HexToHash("0x10")
 	val1 := common.BigToHash(big.NewInt(int64(1)))
@@ -2021,8 +2139,6 @@ func TestLeafWithOneNibble(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness("LeafWithOneNibble", trieModifications, statedb)
-
-	oracle.PreventHashingInSecureTrie = false
 }
 
 /*
@@ -2038,16 +2154,20 @@ func TestLeafWithMoreNibbles(t *testing.T) {
 	
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
 	
 	statedb.CreateAccount(addr)
 
-	oracle.PreventHashingInSecureTrie = true
+	o.PreventHashing = true
 
 	// Let us make the extension node shorter than 55 (although this than causes branch to be hashed):
 	key1 := common.HexToHash("0x100000000000000000000000")
@@ -2075,8 +2195,6 @@ func TestLeafWithMoreNibbles(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness("LeafWithMoreNibbles", trieModifications, statedb)
-
-	oracle.PreventHashingInSecureTrie = false
 }
 
 // Note: this requires MockProver with config param 11
@@ -2084,16 +2202,20 @@ func TestLeafWithMoreNibbles(t *testing.T) {
 func TestNonHashedBranchInBranch(t *testing.T) {
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
 	
 	statedb.CreateAccount(addr)
 
-	oracle.PreventHashingInSecureTrie = true // to store the unchanged key
+	o.PreventHashing = true // to store the unchanged key
 
 	val1 := common.BigToHash(big.NewInt(int64(1)))
 
@@ -2132,8 +2254,6 @@ func TestNonHashedBranchInBranch(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	GenerateProof("NonHashedBranchInBranch", trieModifications, statedb)
-
-	oracle.PreventHashingInSecureTrie = false
 }
 
 func replaceAtIndex(in string, r rune, i int) string {
@@ -2146,16 +2266,20 @@ func replaceAtIndex(in string, r rune, i int) string {
 func TestNonHashedExtensionNodeInBranch(t *testing.T) {
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
 	
 	statedb.CreateAccount(addr)
 
-	oracle.PreventHashingInSecureTrie = true // to store the unchanged key
+	o.PreventHashing = true // to store the unchanged key
 
 	val1 := common.BigToHash(big.NewInt(int64(1)))
 
@@ -2201,24 +2325,26 @@ func TestNonHashedExtensionNodeInBranch(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	GenerateProof("NonHashedExtensionNodeInBranch", trieModifications, statedb)
-
-	oracle.PreventHashingInSecureTrie = false
 }
 
 // Note: this requires MockProver with config param 11
 func TestNonHashedExtensionNodeInBranchTwoNibbles(t *testing.T) {
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
 	
 	statedb.CreateAccount(addr)
 
-	oracle.PreventHashingInSecureTrie = true // to store the unchanged key
+	o.PreventHashing = true // to store the unchanged key
 
 	val1 := common.BigToHash(big.NewInt(int64(1)))
 
@@ -2264,24 +2390,26 @@ func TestNonHashedExtensionNodeInBranchTwoNibbles(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	GenerateProof("NonHashedExtensionNodeInBranchTwoNibbles", trieModifications, statedb)
-
-	oracle.PreventHashingInSecureTrie = false
 }
 */
 
 func TestBranchAfterExtNode(t *testing.T) {
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x40efbf12580138bc623c95757286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
 	
 	statedb.CreateAccount(addr)
 
-	oracle.PreventHashingInSecureTrie = true // to store the unchanged key
+	o.PreventHashing = true // to store the unchanged key
 
 	key1Hex := "0x1000000000000000000000000" 
 	key2Hex := "0x2000000000000000000000000" 
@@ -2316,8 +2444,6 @@ func TestBranchAfterExtNode(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness("BranchAfterExtNode", trieModifications, statedb)
-
-	oracle.PreventHashingInSecureTrie = false
 }
 
 func TestNonExistingStorage(t *testing.T) {
@@ -12,12 +12,15 @@ import (
 
 func TestNonExistingAccountNilObjectInFirstLevel(t *testing.T) {
 	// geth --dev --http --ipcpath ~/Library/Ethereum/geth.ipc
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	i := 21
 	h := fmt.Sprintf("0x%d", i)
@@ -31,19 +34,20 @@ func TestNonExistingAccountNilObjectInFirstLevel(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness("NonExistingAccountNilObjectInFirstLevel", trieModifications, statedb)
-
-	oracle.NodeUrl = oracle.RemoteUrl
 }
 
 func TestNonExistingAccountInFirstLevel(t *testing.T) {
 	// Only one element in the trie - the account with "wrong" address.
 	// geth --dev --http --ipcpath ~/Library/Ethereum/geth.ipc
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	i := 10
 	h := fmt.Sprintf("0x%d", i)
@@ -57,18 +61,19 @@ func TestNonExistingAccountInFirstLevel(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitnessSpecial("NonExistingAccountInFirstLevel", trieModifications, statedb, 4)
-
-	oracle.NodeUrl = oracle.RemoteUrl
 }
 
 func TestNonExistingAccountAfterFirstLevel(t *testing.T) {
 	// geth --dev --http --ipcpath ~/Library/Ethereum/geth.ipc
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	i := 22
 	h := fmt.Sprintf("0x%d", i)
@@ -81,19 +86,20 @@ func TestNonExistingAccountAfterFirstLevel(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness("NonExistingAccountAfterFirstLevel", trieModifications, statedb)
-
-	oracle.NodeUrl = oracle.RemoteUrl
 }
 
 // Account leaf after one branch. No storage proof.
 func TestAccountAfterFirstLevel(t *testing.T) {
 	// geth --dev --http --ipcpath ~/Library/Ethereum/geth.ipc
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	i := 21
 	h := fmt.Sprintf("0x%d", i)
@@ -107,19 +113,20 @@ func TestAccountAfterFirstLevel(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness("AccountAfterFirstLevel", trieModifications, statedb)
-
-	oracle.NodeUrl = oracle.RemoteUrl
 }
 
 // Account leaf in first level in C proof, placeholder leaf in S proof. No storage proof.
 func TestAccountInFirstLevel(t *testing.T) {
 	// geth --dev --http --ipcpath ~/Library/Ethereum/geth.ipc
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	i := 21
 	h := fmt.Sprintf("0x%d", i)
@@ -133,18 +140,19 @@ func TestAccountInFirstLevel(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitnessSpecial("AccountInFirstLevel", trieModifications, statedb, 1)
-
-	oracle.NodeUrl = oracle.RemoteUrl
 }
 
 func TestAccountExtensionInFirstLevel(t *testing.T) {
 	// geth --dev --http --ipcpath ~/Library/Ethereum/geth.ipc
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	h := fmt.Sprintf("0xa21%d", 0)
 	addr := common.HexToAddress(h)
@@ -156,7 +164,7 @@ func TestAccountExtensionInFirstLevel(t *testing.T) {
 		statedb.CreateAccount(addr)
 		statedb.IntermediateRoot(false)
 
-		oracle.PrefetchAccount(statedb.Db.BlockNumber, addr, nil)
+		o.PrefetchAccount(statedb.Db.BlockNumber, addr, nil)
 		proof1, _, _, _, err := statedb.GetProof(addr)
 		check(err)
 
@@ -179,18 +187,19 @@ func TestAccountExtensionInFirstLevel(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitnessSpecial("AccountExtensionInFirstLevel", trieModifications, statedb, 5)
-
-	oracle.NodeUrl = oracle.RemoteUrl
 }
 
 func TestAccountBranchPlaceholder(t *testing.T) {
 	// geth --dev --http --ipcpath ~/Library/Ethereum/geth.ipc
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	h := fmt.Sprintf("0xab%d", 0)
 	addr := common.HexToAddress(h)
@@ -204,25 +213,26 @@ func TestAccountBranchPlaceholder(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness("AccountBranchPlaceholder", trieModifications, statedb)
-
-	oracle.NodeUrl = oracle.RemoteUrl
 }
 
 func TestAccountBranchPlaceholderInFirstLevel(t *testing.T) {
 	// geth --dev --http --ipcpath ~/Library/Ethereum/geth.ipc
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	/*
 	for i := 0; i < 100000; i++ {
 		h := fmt.Sprintf("0xa21%d", i)
 		addr := common.HexToAddress(h)
 
-		oracle.PrefetchAccount(statedb.Db.BlockNumber, addr, nil)
+		o.PrefetchAccount(statedb.Db.BlockNumber, addr, nil)
 		proof1, _, _, _, err := statedb.GetProof(addr)
 		check(err)
 
@@ -232,7 +242,7 @@ func TestAccountBranchPlaceholderInFirstLevel(t *testing.T) {
 		// addrHash1 := crypto.Keccak256Hash(addr.Bytes())
 		// addrHash1[31] = (addrHash1[31] + 1) % 255 // just some change
 
-		// oracle.PrefetchAccount(statedb.Db.BlockNumber, addr1, nil)
+		// o.PrefetchAccount(statedb.Db.BlockNumber, addr1, nil)
 		// proof11, _, _, err := statedb.GetProofByHash(common.BytesToHash(addrHash1.Bytes()))
 
 		statedb.CreateAccount(addr)
@@ -268,18 +278,19 @@ func TestAccountBranchPlaceholderInFirstLevel(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitnessSpecial("AccountBranchPlaceholderInFirstLevel", trieModifications, statedb, 3) // don't use the same number as in the test above
-
-	oracle.NodeUrl = oracle.RemoteUrl
 }
 
 func TestStorageInFirstAccountInFirstLevel(t *testing.T) {
 	// geth --dev --http --ipcpath ~/Library/Ethereum/geth.ipc
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	i := 21
 	h := fmt.Sprintf("0x%d", i)
@@ -294,18 +305,19 @@ func TestStorageInFirstAccountInFirstLevel(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitnessSpecial("StorageInFirstAccountInFirstLevel", trieModifications, statedb, 1)
-
-	oracle.NodeUrl = oracle.RemoteUrl
 }
 
 func TestExtensionTwoNibblesInEvenLevel(t *testing.T) {
 	// geth --dev --http --ipcpath ~/Library/Ethereum/geth.ipc
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	h := fmt.Sprintf("0xa21%d", 0)
 	addr := common.HexToAddress(h)
@@ -317,7 +329,7 @@ func TestExtensionTwoNibblesInEvenLevel(t *testing.T) {
 		statedb.CreateAccount(addr)
 		statedb.IntermediateRoot(false)
 
-		oracle.PrefetchAccount(statedb.Db.BlockNumber, addr, nil)
+		o.PrefetchAccount(statedb.Db.BlockNumber, addr, nil)
 		proof1, _, _, _, err := statedb.GetProof(addr)
 		check(err)
 
@@ -341,18 +353,19 @@ func TestExtensionTwoNibblesInEvenLevel(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness("AccountExtensionTwoNibblesInEvenLevel", trieModifications, statedb)
-
-	oracle.NodeUrl = oracle.RemoteUrl
 }
 
 func TestExtensionThreeNibblesInEvenLevel(t *testing.T) {
 	// geth --dev --http --ipcpath ~/Library/Ethereum/geth.ipc
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	h := fmt.Sprintf("0xa21%d", 0)
 	addr := common.HexToAddress(h)
@@ -364,7 +377,7 @@ func TestExtensionThreeNibblesInEvenLevel(t *testing.T) {
 		statedb.CreateAccount(addr)
 		statedb.IntermediateRoot(false)
 
-		oracle.PrefetchAccount(statedb.Db.BlockNumber, addr, nil)
+		o.PrefetchAccount(statedb.Db.BlockNumber, addr, nil)
 		proof1, _, _, _, err := statedb.GetProof(addr)
 		check(err)
 
@@ -388,18 +401,19 @@ func TestExtensionThreeNibblesInEvenLevel(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness("AccountExtensionThreeNibblesInEvenLevel", trieModifications, statedb)
-
-	oracle.NodeUrl = oracle.RemoteUrl
 }
 
 func TestExtensionThreeNibblesInOddLevel(t *testing.T) {
 	// geth --dev --http --ipcpath ~/Library/Ethereum/geth.ipc
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	h := fmt.Sprintf("0xa21%d", 0)
 	addr := common.HexToAddress(h)
@@ -411,7 +425,7 @@ func TestExtensionThreeNibblesInOddLevel(t *testing.T) {
 		statedb.CreateAccount(addr)
 		statedb.IntermediateRoot(false)
 
-		oracle.PrefetchAccount(statedb.Db.BlockNumber, addr, nil)
+		o.PrefetchAccount(statedb.Db.BlockNumber, addr, nil)
 		proof1, _, _, _, err := statedb.GetProof(addr)
 		check(err)
 
@@ -435,18 +449,19 @@ func TestExtensionThreeNibblesInOddLevel(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness("AccountExtensionThreeNibblesInOddLevel", trieModifications, statedb)
-
-	oracle.NodeUrl = oracle.RemoteUrl
 }
 
 func TestStorageInFirstLevelNonExisting(t *testing.T) {
 	// geth --dev --http --ipcpath ~/Library/Ethereum/geth.ipc
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	i := 21
 	h := fmt.Sprintf("0x%d", i)
@@ -464,18 +479,19 @@ func TestStorageInFirstLevelNonExisting(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness("StorageInFirstLevelNonExisting", trieModifications, statedb)
-
-	oracle.NodeUrl = oracle.RemoteUrl
 }
 
 func TestStorageInFirstLevelNonExistingLong(t *testing.T) {
 	// geth --dev --http --ipcpath ~/Library/Ethereum/geth.ipc
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	i := 21
 	h := fmt.Sprintf("0x%d", i)
@@ -494,23 +510,24 @@ func TestStorageInFirstLevelNonExistingLong(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness("StorageInFirstLevelNonExistingLong", trieModifications, statedb)
-
-	oracle.NodeUrl = oracle.RemoteUrl
 }
 
 func ExtNodeInserted(key1, key2, key3 common.Hash, testName string) {
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
 	statedb.CreateAccount(addr)
-	oracle.PreventHashingInSecureTrie = true // to store the unchanged key
+	o.PreventHashing = true // to store the unchanged key
 
 	// make the value long to have a hashed branch
 	v1 := common.FromHex("0xbbefaa12580138bc263c95757826df4e24eb81c9aaaaaaaaaaaaaaaaaaaaaaaa")
@@ -531,23 +548,24 @@ func ExtNodeInserted(key1, key2, key3 common.Hash, testName string) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness(testName, trieModifications, statedb)
-
-	oracle.PreventHashingInSecureTrie = false
 }
 
 func ExtNodeDeleted(key1, key2, key3 common.Hash, testName string) {
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
 	statedb.CreateAccount(addr)
-	oracle.PreventHashingInSecureTrie = true // to store the unchanged key
+	o.PreventHashing = true // to store the unchanged key
 
 	// make the value long to have a hashed branch
 	v1 := common.FromHex("0xbbefaa12580138bc263c95757826df4e24eb81c9aaaaaaaaaaaaaaaaaaaaaaaa")
@@ -568,8 +586,6 @@ func ExtNodeDeleted(key1, key2, key3 common.Hash, testName string) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness(testName, trieModifications, statedb)
-
-	oracle.PreventHashingInSecureTrie = false
 }
 
 func TestExtNodeInsertedBefore6After1FirstLevel(t *testing.T) {
@@ -650,20 +666,23 @@ func TestExtNodeInsertedBefore5After1FirstLevel(t *testing.T) {
 }
 
 func TestExtNodeInsertedBefore4After1(t *testing.T) {
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
 	
 	statedb.CreateAccount(addr)
 
-	oracle.PreventHashingInSecureTrie = true // to store the unchanged key
+	o.PreventHashing = true // to store the unchanged key
 
 	val0 := common.BigToHash(big.NewInt(int64(1)))
 	key0 := common.HexToHash("0x1000000000000000000000000000000000000000000000000000000000000000")
@@ -704,25 +723,26 @@ func TestExtNodeInsertedBefore4After1(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness("ExtNodeInsertedBefore4After1", trieModifications, statedb)
-
-	oracle.PreventHashingInSecureTrie = false
 }
 
 func TestExtNodeDeletedBefore4After1(t *testing.T) {
-	oracle.NodeUrl = oracle.LocalUrl
+	o := oracle.NewRPCOracle(oracle.LocalUrl)
 
 	blockNum := 0
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
 
 	statedb.DisableLoadingRemoteAccounts()
 	
 	statedb.CreateAccount(addr)
 
-	oracle.PreventHashingInSecureTrie = true // to store the unchanged key
+	o.PreventHashing = true // to store the unchanged key
 
 	val0 := common.BigToHash(big.NewInt(int64(1)))
 	key0 := common.HexToHash("0x1000000000000000000000000000000000000000000000000000000000000000")
@@ -755,8 +775,6 @@ func TestExtNodeDeletedBefore4After1(t *testing.T) {
 	trieModifications := []TrieModification{trieMod}
 
 	prepareWitness("ExtNodeDeletedBefore4After1", trieModifications, statedb)
-
-	oracle.PreventHashingInSecureTrie = false
 }
 
 func TestExtNodeInNewBranchFirstLevel(t *testing.T) {
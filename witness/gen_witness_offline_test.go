@@ -0,0 +1,171 @@
+package witness
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/state"
+)
+
+// The tests in this file build the state from the genesis alloc in memory, without any
+// node (not even devnode).
+
+func offlineStateDB(t *testing.T, alloc core.GenesisAlloc) *state.StateDB {
+	statedb, err := state.NewFromAlloc(alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statedb
+}
+
+func TestOfflineWitnessSameAsDevnode(t *testing.T) {
+	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
+	alloc := core.GenesisAlloc{
+		addr: {
+			Balance: big.NewInt(1),
+			Nonce:   2,
+			Code:    []byte{0x60, 0x01},
+			Storage: map[common.Hash]common.Hash{
+				common.HexToHash("0x1"): common.HexToHash("0x11"),
+				common.HexToHash("0x2"): common.HexToHash("0x22"),
+			},
+		},
+		common.HexToAddress("0x1"): {Balance: big.NewInt(1)},
+		common.HexToAddress("0x2"): {Balance: big.NewInt(2)},
+	}
+	trieModifications := []TrieModification{
+		{Type: StorageChanged, Address: addr, Key: common.HexToHash("0x1"), Value: common.HexToHash("0x17")},
+		{Type: StorageChanged, Address: addr, Key: common.HexToHash("0x3"), Value: common.HexToHash("0x33")},
+		{Type: BalanceChanged, Address: common.HexToAddress("0x1"), Balance: big.NewInt(7)},
+		{Type: NonceChanged, Address: addr, Nonce: 3},
+	}
+
+	offline := offlineStateDB(t, alloc)
+	online := devnodeStateDB(t, alloc)
+	if offline.IntermediateRoot(false) != online.IntermediateRoot(false) {
+		t.Fatal("the state roots differ")
+	}
+	if code := offline.GetCode(addr); !reflect.DeepEqual(code, []byte{0x60, 0x01}) {
+		t.Fatalf("wrong code %x", code)
+	}
+
+	offlineNodes, err := obtainTwoProofsAndConvertToWitness(trieModifications, offline, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	onlineNodes, err := obtainTwoProofsAndConvertToWitness(trieModifications, online, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offlineNodes) == 0 || !reflect.DeepEqual(offlineNodes, onlineNodes) {
+		t.Fatal("the witness differs from the one generated with devnode")
+	}
+	if err := offline.Error(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOfflineWitnessAfterCommit(t *testing.T) {
+	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
+	key1, key2, key3 := common.HexToHash("0x1"), common.HexToHash("0x2"), common.HexToHash("0x3")
+	statedb := offlineStateDB(t, core.GenesisAlloc{
+		addr:                       {Balance: big.NewInt(1), Storage: map[common.Hash]common.Hash{key1: common.HexToHash("0x11")}},
+		common.HexToAddress("0x1"): {Balance: big.NewInt(1)},
+	})
+
+	first := []TrieModification{
+		{Type: StorageChanged, Address: addr, Key: key2, Value: common.HexToHash("0x22")},
+		{Type: CodeHashChanged, Address: addr, CodeHash: []byte{0x60, 0x01}},
+	}
+	if _, err := obtainTwoProofsAndConvertToWitness(first, statedb, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second witness starts from the committed state, all its nodes are resolved.
+	reopened, err := state.New(root, statedb.Db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := []TrieModification{
+		{Type: StorageChanged, Address: addr, Key: key1, Value: common.HexToHash("0x12")},
+		{Type: StorageChanged, Address: addr, Key: key3, Value: common.HexToHash("0x33")},
+		{Type: BalanceChanged, Address: common.HexToAddress("0x1"), Balance: big.NewInt(2)},
+	}
+	if _, err := obtainTwoProofsAndConvertToWitness(second, reopened, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if code := reopened.GetCode(addr); !reflect.DeepEqual(code, []byte{0x60, 0x01}) {
+		t.Fatalf("wrong code %x", code)
+	}
+
+	expected := offlineStateDB(t, core.GenesisAlloc{
+		addr: {
+			Balance: big.NewInt(1),
+			Code:    []byte{0x60, 0x01},
+			Storage: map[common.Hash]common.Hash{
+				key1: common.HexToHash("0x12"),
+				key2: common.HexToHash("0x22"),
+				key3: common.HexToHash("0x33"),
+			},
+		},
+		common.HexToAddress("0x1"): {Balance: big.NewInt(2)},
+	})
+	if reopened.IntermediateRoot(false) != expected.IntermediateRoot(false) {
+		t.Fatal("the state root after both witnesses differs from the expected state")
+	}
+}
+
+func TestChainedWitness(t *testing.T) {
+	addr := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9")
+	alloc := core.GenesisAlloc{
+		addr: {
+			Balance: big.NewInt(1),
+			Storage: map[common.Hash]common.Hash{common.HexToHash("0x1"): common.HexToHash("0x11")},
+		},
+		common.HexToAddress("0x1"): {Balance: big.NewInt(1)},
+		common.HexToAddress("0x2"): {Balance: big.NewInt(2)},
+	}
+	// The account modifications are among the storage ones, their witnesses are chained.
+	trieModifications := []TrieModification{
+		{Type: BalanceChanged, Address: common.HexToAddress("0x1"), Balance: big.NewInt(7)},
+		{Type: StorageChanged, Address: addr, Key: common.HexToHash("0x1"), Value: common.HexToHash("0x17")},
+		{Type: NonceChanged, Address: addr, Nonce: 3},
+		{Type: BalanceChanged, Address: common.HexToAddress("0x2"), Balance: big.NewInt(8)},
+		{Type: StorageChanged, Address: addr, Key: common.HexToHash("0x2"), Value: common.HexToHash("0x22")},
+	}
+	nodes, err := obtainTwoProofsAndConvertToWitness(trieModifications, offlineStateDB(t, alloc), 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The chained witness consists of the witnesses of the modifications one by one.
+	var expected []Node
+	statedb := offlineStateDB(t, alloc)
+	for _, tMod := range trieModifications {
+		modNodes, err := obtainTwoProofsAndConvertToWitness([]TrieModification{tMod}, statedb, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, modNodes...)
+	}
+	if !reflect.DeepEqual(nodes, expected) {
+		t.Fatal("the chained witness differs from the witnesses of the single modifications")
+	}
+	if types := proofTypes(nodes); !reflect.DeepEqual(types, []string{"BalanceChanged", "StorageChanged", "NonceChanged", "BalanceChanged", "StorageChanged"}) {
+		t.Fatalf("unexpected proof types %v", types)
+	}
+	sRoot, cRoot := witnessRoots(nodes)
+	if sRoot != offlineStateDB(t, alloc).IntermediateRoot(false) || cRoot != statedb.IntermediateRoot(false) {
+		t.Fatalf("witness from %s to %s doesn't span the modifications", sRoot, cRoot)
+	}
+}
@@ -0,0 +1,50 @@
+package witness
+
+import (
+	"math/big"
+
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/state"
+)
+
+// ModificationsFromJournal returns the modifications made to statedb (with the usual
+// StateDB methods) since its last Finalise or IntermediateRoot, as recorded in its journal.
+// Each changed field of an account gives one modification with the last value of the
+// field, the accounts are in the order of their first change.
+//
+// The modifications are to be applied to a StateDB opened at the state before the changes,
+// statedb already has them.
+func ModificationsFromJournal(statedb *state.StateDB) []TrieModification {
+	var trieModifications []TrieModification
+	for _, change := range statedb.JournalChanges() {
+		addr := change.Address
+		if change.Destructed {
+			if change.StorageWiped {
+				trieModifications = append(trieModifications, TrieModification{Type: StorageWiped, Address: addr})
+			}
+			trieModifications = append(trieModifications, TrieModification{Type: AccountDestructed, Address: addr})
+			continue
+		}
+		if change.Touched {
+			// The touch is a change of the balance to the same value, the account is
+			// removed after it when the empty accounts are deleted.
+			trieModifications = append(trieModifications, TrieModification{Type: BalanceChanged, Address: addr, Balance: new(big.Int)})
+			continue
+		}
+		if change.Created {
+			trieModifications = append(trieModifications, TrieModification{Type: AccountCreate, Address: addr})
+		}
+		if change.Nonce != nil {
+			trieModifications = append(trieModifications, TrieModification{Type: NonceChanged, Address: addr, Nonce: *change.Nonce})
+		}
+		if change.Balance != nil {
+			trieModifications = append(trieModifications, TrieModification{Type: BalanceChanged, Address: addr, Balance: change.Balance})
+		}
+		if change.CodeChanged {
+			trieModifications = append(trieModifications, TrieModification{Type: CodeHashChanged, Address: addr, CodeHash: change.Code})
+		}
+		for _, slot := range change.Storage {
+			trieModifications = append(trieModifications, TrieModification{Type: StorageChanged, Address: addr, Key: slot.Key, Value: slot.Value})
+		}
+	}
+	return trieModifications
+}
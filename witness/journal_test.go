@@ -0,0 +1,137 @@
+package witness
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+)
+
+func TestModificationsFromJournal(t *testing.T) {
+	a, b, c, d := common.HexToAddress("0xa"), common.HexToAddress("0xb"), common.HexToAddress("0xc"), common.HexToAddress("0xd")
+	k1, k2, k3 := common.HexToHash("0x1"), common.HexToHash("0x2"), common.HexToHash("0x3")
+	alloc := core.GenesisAlloc{
+		a: {Balance: big.NewInt(10), Nonce: 1, Storage: map[common.Hash]common.Hash{k1: common.HexToHash("0x11"), k2: common.HexToHash("0x22")}},
+		b: {Balance: big.NewInt(5)},
+		c: {Balance: big.NewInt(7)},
+	}
+	statedb := offlineStateDB(t, alloc)
+
+	statedb.SetBalance(a, big.NewInt(20))
+	statedb.SetBalance(a, big.NewInt(10)) // no net change
+	statedb.SubBalance(a, big.NewInt(3))
+	statedb.AddBalance(b, big.NewInt(3))
+	statedb.SetNonce(a, 2)
+	statedb.SetState(a, k1, common.HexToHash("0x12"))
+	statedb.SetState(a, k1, common.HexToHash("0x13"))
+	statedb.SetState(a, k2, common.HexToHash("0x23"))
+	statedb.SetState(a, k2, common.HexToHash("0x22")) // no net change
+	statedb.SetState(a, k3, common.HexToHash("0x33"))
+
+	snapshot := statedb.Snapshot()
+	statedb.SetState(a, k1, common.HexToHash("0x99"))
+	statedb.SetBalance(c, big.NewInt(100))
+	statedb.RevertToSnapshot(snapshot)
+
+	statedb.CreateAccount(d)
+	statedb.SetBalance(d, big.NewInt(4))
+	statedb.SetCode(d, []byte{0x60, 0x01})
+	statedb.Suicide(c)
+
+	trieModifications := ModificationsFromJournal(statedb)
+	expected := []TrieModification{
+		{Type: NonceChanged, Address: a, Nonce: 2},
+		{Type: BalanceChanged, Address: a, Balance: big.NewInt(7)},
+		{Type: StorageChanged, Address: a, Key: k1, Value: common.HexToHash("0x13")},
+		{Type: StorageChanged, Address: a, Key: k3, Value: common.HexToHash("0x33")},
+		{Type: BalanceChanged, Address: b, Balance: big.NewInt(8)},
+		{Type: AccountCreate, Address: d},
+		{Type: BalanceChanged, Address: d, Balance: big.NewInt(4)},
+		{Type: CodeHashChanged, Address: d, CodeHash: []byte{0x60, 0x01}},
+		{Type: AccountDestructed, Address: c},
+	}
+	if len(trieModifications) != len(expected) {
+		t.Fatalf("expected %d modifications, got %d: %+v", len(expected), len(trieModifications), trieModifications)
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(trieModifications[i], expected[i]) {
+			t.Errorf("modification %d is %+v, expected %+v", i, trieModifications[i], expected[i])
+		}
+	}
+
+	// The witness for the modifications goes from the state of alloc to the state of statedb.
+	fresh := offlineStateDB(t, alloc)
+	start := fresh.IntermediateRoot(false)
+	nodes, err := obtainTwoProofsAndConvertToWitness(trieModifications, fresh, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.IntermediateRoot(false) != statedb.IntermediateRoot(false) {
+		t.Fatal("the state root after the witness differs from the modified state")
+	}
+	sRoot, cRoot := witnessRoots(nodes)
+	if sRoot != start || cRoot != statedb.IntermediateRoot(false) {
+		t.Fatalf("witness from %s to %s, expected from %s to %s", sRoot, cRoot, start, statedb.IntermediateRoot(false))
+	}
+	// AccountCreate has the NonceChanged proof type.
+	expectedTypes := []string{"NonceChanged", "BalanceChanged", "StorageChanged", "StorageChanged", "BalanceChanged",
+		"NonceChanged", "BalanceChanged", "CodeHashExists", "AccountDestructed"}
+	if types := proofTypes(nodes); !reflect.DeepEqual(types, expectedTypes) {
+		t.Fatalf("proof types %v, expected %v", types, expectedTypes)
+	}
+
+	// The journal is cleared by IntermediateRoot.
+	if trieModifications := ModificationsFromJournal(statedb); len(trieModifications) != 0 {
+		t.Fatalf("unexpected modifications %+v", trieModifications)
+	}
+}
+
+func TestModificationsFromJournalReplacedAndDestructed(t *testing.T) {
+	a := destructAddr
+	k1, k2 := destructKeys[0], destructKeys[1]
+	// a is pre-funded and has storage, like an address which a contract is deployed to
+	// with CREATE2 and which self-destructs in the init code.
+	alloc := core.GenesisAlloc{
+		a:                          {Balance: big.NewInt(10), Storage: map[common.Hash]common.Hash{k1: common.HexToHash("0x11")}},
+		common.HexToAddress("0x1"): {Balance: big.NewInt(1)},
+		common.HexToAddress("0x3"): {Balance: big.NewInt(3)},
+	}
+	statedb := offlineStateDB(t, alloc)
+	start := statedb.IntermediateRoot(false)
+
+	statedb.CreateAccount(a)
+	statedb.SetNonce(a, 1)
+	statedb.SetState(a, k2, common.HexToHash("0x22"))
+	statedb.Suicide(a)
+
+	trieModifications := ModificationsFromJournal(statedb)
+	expected := []TrieModification{
+		{Type: StorageWiped, Address: a},
+		{Type: AccountDestructed, Address: a},
+	}
+	if !reflect.DeepEqual(trieModifications, expected) {
+		t.Fatalf("modifications %+v, expected %+v", trieModifications, expected)
+	}
+
+	fresh := offlineStateDB(t, alloc)
+	nodes, err := obtainTwoProofsAndConvertToWitness(trieModifications, fresh, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sRoot, cRoot := witnessRoots(nodes)
+	if sRoot != start || cRoot != statedb.IntermediateRoot(false) {
+		t.Fatalf("witness from %s to %s, expected from %s to %s", sRoot, cRoot, start, statedb.IntermediateRoot(false))
+	}
+
+	// An account created by the changes and destructed is not in the modifications, even
+	// when it is created again before it self-destructs.
+	c := common.HexToAddress("0xc")
+	statedb.CreateAccount(c)
+	statedb.CreateAccount(c)
+	statedb.Suicide(c)
+	if trieModifications := ModificationsFromJournal(statedb); len(trieModifications) != 0 {
+		t.Fatalf("unexpected modifications %+v", trieModifications)
+	}
+}
@@ -0,0 +1,55 @@
+package witness
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/state"
+)
+
+// OverrideAccount is the override of an account, in the format of the state override set
+// of eth_call. State replaces the whole storage of the account, StateDiff only the given
+// slots, at most one of them can be set.
+type OverrideAccount struct {
+	Nonce     *hexutil.Uint64             `json:"nonce"`
+	Code      *hexutil.Bytes              `json:"code"`
+	Balance   *hexutil.Big                `json:"balance"`
+	State     map[common.Hash]common.Hash `json:"state"`
+	StateDiff map[common.Hash]common.Hash `json:"stateDiff"`
+}
+
+// StateOverride is the set of account overrides which are applied to the state of the
+// block before the modifications, the S root of the first modification is then the root
+// of the overridden state.
+type StateOverride map[common.Address]OverrideAccount
+
+// Apply applies the overrides to statedb.
+func (diff StateOverride) Apply(statedb *state.StateDB) error {
+	for addr, account := range diff {
+		if account.State != nil && account.StateDiff != nil {
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
+		}
+		if account.State != nil {
+			// The storage starts empty, the balance, the nonce and the code are kept.
+			statedb.WipeStorage(addr)
+			for key, value := range account.State {
+				statedb.SetState(addr, key, value)
+			}
+		}
+		if account.Nonce != nil {
+			statedb.SetNonce(addr, uint64(*account.Nonce))
+		}
+		if account.Code != nil {
+			statedb.SetCode(addr, *account.Code)
+		}
+		if account.Balance != nil {
+			statedb.SetBalance(addr, (*big.Int)(account.Balance))
+		}
+		for key, value := range account.StateDiff {
+			statedb.SetState(addr, key, value)
+		}
+	}
+	return statedb.Error()
+}
@@ -0,0 +1,125 @@
+package witness
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/devnode"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/oracle"
+)
+
+// witnessRoots returns the S root of the first modification and the C root of the last
+// one in the chained witness.
+func witnessRoots(nodes []Node) (common.Hash, common.Hash) {
+	var starts []Node
+	for _, node := range nodes {
+		if node.Start != nil && node.Start.ProofType != "Disabled" {
+			starts = append(starts, node)
+		}
+	}
+	first, last := starts[0], starts[len(starts)-1]
+	return common.BytesToHash(first.Values[0][1:33]), common.BytesToHash(last.Values[1][1:33])
+}
+
+func TestGetWitnessStateOverride(t *testing.T) {
+	addr, other := common.HexToAddress("0x50efbf12580138bc623c95757286df4e24eb81c9"), common.HexToAddress("0x1")
+	k1, k2, k3 := common.HexToHash("0x1"), common.HexToHash("0x2"), common.HexToHash("0x3")
+	alloc := core.GenesisAlloc{
+		addr: {
+			Balance: big.NewInt(1),
+			Storage: map[common.Hash]common.Hash{k1: common.HexToHash("0x11"), k2: common.HexToHash("0x22")},
+		},
+		other: {Balance: big.NewInt(1), Storage: map[common.Hash]common.Hash{k1: common.HexToHash("0x11")}},
+	}
+	node, err := devnode.New(alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(node)
+	defer server.Close()
+	block := oracle.BlockNumberRef(big.NewInt(0))
+
+	trieModifications := []TrieModification{
+		{Type: StorageChanged, Address: addr, Key: k1, Value: common.HexToHash("0x17")},
+		{Type: StorageChanged, Address: other, Key: k1, Value: common.HexToHash("0x18")},
+	}
+	// expectedRoots returns the roots of the state given by alloc and the overrides,
+	// before and after the modifications.
+	expectedRoots := func(overrides StateOverride) (common.Hash, common.Hash) {
+		statedb := offlineStateDB(t, alloc)
+		if err := overrides.Apply(statedb); err != nil {
+			t.Fatal(err)
+		}
+		s := statedb.IntermediateRoot(false)
+		for _, tMod := range trieModifications {
+			statedb.SetState(tMod.Address, tMod.Key, tMod.Value)
+		}
+		return s, statedb.IntermediateRoot(false)
+	}
+
+	// Without overrides the witness starts at the state of the block.
+	nodes, err := GetWitness(server.URL, block, trieModifications)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sRoot, cRoot := witnessRoots(nodes)
+	if sRoot != node.Root() {
+		t.Fatalf("S root %s, expected the state root %s", sRoot, node.Root())
+	}
+	if _, expected := expectedRoots(nil); cRoot != expected {
+		t.Fatalf("C root %s, expected %s", cRoot, expected)
+	}
+
+	nonce, balance := hexutil.Uint64(5), hexutil.Big(*big.NewInt(100))
+	code := hexutil.Bytes{0x60, 0x01}
+	overrides := StateOverride{
+		// The storage of addr is replaced, k2 is removed.
+		addr:  {Nonce: &nonce, Code: &code, State: map[common.Hash]common.Hash{k3: common.HexToHash("0x33")}},
+		other: {Balance: &balance, StateDiff: map[common.Hash]common.Hash{k2: common.HexToHash("0x22")}},
+	}
+	nodes, err = GetWitness(server.URL, block, trieModifications, WithStateOverride(overrides))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sRoot, cRoot = witnessRoots(nodes)
+	expectedS, expectedC := expectedRoots(overrides)
+	if sRoot == node.Root() || sRoot != expectedS {
+		t.Fatalf("S root %s, expected the overridden state root %s", sRoot, expectedS)
+	}
+	if cRoot != expectedC {
+		t.Fatalf("C root %s, expected %s", cRoot, expectedC)
+	}
+
+	invalid := StateOverride{addr: {State: map[common.Hash]common.Hash{}, StateDiff: map[common.Hash]common.Hash{}}}
+	if _, err := GetWitness(server.URL, block, trieModifications, WithStateOverride(invalid)); err == nil {
+		t.Fatal("expected an error for both state and stateDiff")
+	}
+}
+
+func TestGetWitnessReplayMiss(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oracle-fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("MPT_ORACLE_CACHE_MODE", "replay")
+	defer os.Unsetenv("MPT_ORACLE_CACHE_MODE")
+	os.Setenv("MPT_ORACLE_CACHE_DIR", dir)
+	defer os.Unsetenv("MPT_ORACLE_CACHE_DIR")
+
+	// The missing fixture is returned to the caller (the witness_gen_wrapper.go returns it
+	// in the JSON), it doesn't stop the process.
+	trieModifications := []TrieModification{{Type: BalanceChanged, Address: common.HexToAddress("0x1"), Balance: big.NewInt(1)}}
+	_, err = GetWitness("http://localhost:1", oracle.BlockNumberRef(big.NewInt(0)), trieModifications)
+	var missErr *oracle.FixtureMissError
+	if !errors.As(err, &missErr) {
+		t.Fatalf("expected FixtureMissError, got %v", err)
+	}
+}
@@ -62,8 +62,58 @@ const (
     StorageChanged
     StorageDoesNotExist
 	AccountCreate
+	// StorageWiped replaces the storage of the account by the empty storage (the storage
+	// root in the account leaf becomes the empty root). It precedes AccountDestructed for
+	// an account with storage: the witness then proves that the storage went away too.
+	StorageWiped
+	// AccountRead and StorageRead prove the value of an account or a storage slot which
+	// is read but not changed, S and C are the same.
+	AccountRead
+	StorageRead
 )
 
+// touchesAccount returns whether the proof type is a change of an existing account, after
+// which the account is removed if it is empty (EIP-161).
+func (t ProofType) touchesAccount() bool {
+	return t == NonceChanged || t == BalanceChanged || t == CodeHashChanged || t == StorageChanged || t == StorageWiped
+}
+
+// nextModification returns the type of the first of trieModifications which is at addr,
+// false if there is none.
+func nextModification(trieModifications []TrieModification, addr common.Address) (ProofType, bool) {
+	for _, tMod := range trieModifications {
+		if tMod.Address == addr {
+			return tMod.Type, true
+		}
+	}
+	return Disabled, false
+}
+
+// deletesEmpty returns whether the account at addr, empty after trieModifications[i], is
+// removed after it (EIP-161).
+func deletesEmpty(trieModifications []TrieModification, i int) bool {
+	tMod := trieModifications[i]
+	if !tMod.Type.touchesAccount() && tMod.Type != AccountCreate {
+		return false
+	}
+	next, ok := nextModification(trieModifications[i+1:], tMod.Address)
+	if ok && (next == StorageWiped || next == AccountDestructed) {
+		// The next modification destructs the account, it isn't destructed twice.
+		return false
+	}
+	if tMod.Type == AccountCreate && ok && next.touchesAccount() {
+		// The created account gets its nonce, balance, code or storage with the next
+		// modification.
+		return false
+	}
+	return true
+}
+
+// isStorage returns whether the proof type is a proof of a storage slot.
+func (t ProofType) isStorage() bool {
+	return t == StorageChanged || t == StorageDoesNotExist || t == StorageRead
+}
+
 type TrieModification struct {
 	Type     ProofType
 	Key      common.Hash
@@ -72,45 +122,129 @@ type TrieModification struct {
 	Nonce    uint64
 	Balance  *big.Int
 	CodeHash []byte
+
+	// AddressHash and KeyHash are the keys in the tries, set by ModificationsBetween.
+	AddressHash common.Hash
+	KeyHash     common.Hash
 }
 
-// GetWitness is to be used by external programs to generate the witness. 
-func GetWitness(nodeUrl string, blockNum int, trieModifications []TrieModification) []Node {
-	blockNumberParent := big.NewInt(int64(blockNum))
-	oracle.NodeUrl = nodeUrl
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+// Option configures GetWitness.
+type Option func(*witnessConfig)
 
-	for i := 0; i < len(trieModifications); i++ {
-		// TODO: remove SetState (using it now just because this particular key might
-		// not be set and we will obtain empty storageProof)
-		v := common.BigToHash(big.NewInt(int64(17)))
-		statedb.SetState(trieModifications[i].Address, trieModifications[i].Key, v)
-		// TODO: enable GetState to get the preimages -
-		// GetState calls GetCommittedState which calls PrefetchStorage to get the preimages
-		// statedb.GetState(addr, keys[i])
+type witnessConfig struct {
+	overrides          StateOverride
+	deleteEmptyObjects bool
+	oracleOpts         []oracle.Option
+}
+
+// WithStateOverride applies overrides to the state of the block before the modifications,
+// the witness then starts at the root of the overridden state.
+func WithStateOverride(overrides StateOverride) Option {
+	return func(c *witnessConfig) {
+		c.overrides = overrides
+	}
+}
+
+// WithDeleteEmptyObjects removes the accounts emptied by the modifications (EIP-161), as
+// the chains do since Spurious Dragon.
+func WithDeleteEmptyObjects(deleteEmptyObjects bool) Option {
+	return func(c *witnessConfig) {
+		c.deleteEmptyObjects = deleteEmptyObjects
+	}
+}
+
+// WithOracleOptions configures the oracle of GetWitness with opts (e.g.
+// oracle.WithPreimageStore to share a preimage store between the calls).
+func WithOracleOptions(opts ...oracle.Option) Option {
+	return func(c *witnessConfig) {
+		c.oracleOpts = append(c.oracleOpts, opts...)
+	}
+}
+
+// GetWitness is to be used by external programs to generate the witness for the state
+// of the given block (a number, a tag like oracle.FinalizedBlock, or a hash).
+// Without options the witness starts at the state root of the block and the emptied
+// accounts stay in the trie. The oracle is closed before GetWitness returns.
+// The errors of the node (see oracle/errors.go) are returned to the caller.
+func GetWitness(nodeUrl string, block oracle.BlockRef, trieModifications []TrieModification, opts ...Option) ([]Node, error) {
+	var config witnessConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	o := oracle.NewRPCOracle(nodeUrl, config.oracleOpts...)
+	defer o.Close()
+	blockHeaderParent, err := o.PrefetchBlock(block, true, nil)
+	if err != nil {
+		return nil, err
+	}
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.overrides.Apply(statedb); err != nil {
+		return nil, err
+	}
+	if err := statedb.Error(); err != nil {
+		return nil, err
+	}
+
+	// obtainTwoProofsAndConvertToWitness prefetches the proofs of the modifications.
+	return obtainTwoProofsAndConvertToWitness(trieModifications, statedb, 0, config.deleteEmptyObjects)
+}
+
+// prefetchTrieModifications fetches the proofs of all accounts and storage keys touched by
+// trieModifications at once, the conversion then doesn't wait for the node for each of
+// the modifications.
+func prefetchTrieModifications(trieModifications []TrieModification, statedb *state.StateDB) error {
+	// The keys are not hashed in the special tests, the node can't prove them.
+	if statedb.Db.Oracle.PreventHashingInSecureTrie() {
+		return nil
 	}
+	return statedb.Db.Oracle.Prefetch(statedb.Db.BlockNumber, modificationKeys(trieModifications))
+}
 
-	return obtainTwoProofsAndConvertToWitness(trieModifications, statedb, 0)
+// modificationKeys lists the accounts and the storage keys touched by trieModifications.
+func modificationKeys(trieModifications []TrieModification) []oracle.StorageKeys {
+	var accounts []oracle.StorageKeys
+	index := make(map[common.Address]int)
+	for _, tMod := range trieModifications {
+		i, ok := index[tMod.Address]
+		if !ok {
+			i = len(accounts)
+			index[tMod.Address] = i
+			accounts = append(accounts, oracle.StorageKeys{Address: tMod.Address})
+		}
+		if tMod.Type.isStorage() {
+			accounts[i].Keys = append(accounts[i].Keys, tMod.Key)
+		}
+	}
+	return accounts
 }
 
-func obtainAccountProofAndConvertToWitness(i int, tMod TrieModification, tModsLen int, statedb *state.StateDB, specialTest byte) []Node {
+func obtainAccountProofAndConvertToWitness(i int, tMod TrieModification, tModsLen int, statedb *state.StateDB, specialTest byte) ([]Node, error) {
 	statedb.IntermediateRoot(false)
 
 	addr := tMod.Address
 	addrh := crypto.Keccak256(addr.Bytes())
 	accountAddr := trie.KeybytesToHex(addrh)
 
-	// This needs to called before oracle.PrefetchAccount, otherwise oracle.PrefetchAccount
+	// This needs to called before Oracle.PrefetchAccount, otherwise Oracle.PrefetchAccount
 	// will cache the proof and won't return it.
-	// Calling oracle.PrefetchAccount after statedb.SetStateObjectIfExists is needed only
+	// Calling Oracle.PrefetchAccount after statedb.SetStateObjectIfExists is needed only
 	// for cases when statedb.loadRemoteAccountsIntoStateObjects = false.
 	statedb.SetStateObjectIfExists(tMod.Address)
 
-	oracle.PrefetchAccount(statedb.Db.BlockNumber, tMod.Address, nil)
+	if _, err := statedb.Db.Oracle.PrefetchAccount(statedb.Db.BlockNumber, tMod.Address, nil); err != nil {
+		return nil, err
+	}
 	accountProof, aNeighbourNode1, aExtNibbles1, isLastLeaf1, err := statedb.GetProof(addr)
-	check(err)
+	if err != nil {
+		return nil, err
+	}
 
 	var nodes []Node
 
@@ -126,15 +260,23 @@ func obtainAccountProofAndConvertToWitness(i int, tMod TrieModification, tModsLe
 		statedb.CreateAccount(tMod.Address)
 	} else if tMod.Type == AccountDestructed {
 		statedb.DeleteAccount(tMod.Address)
+	} else if tMod.Type == StorageWiped {
+		statedb.WipeStorage(tMod.Address)
 	}
-	// No statedb change in case of AccountDoesNotExist.
+	// No statedb change in case of AccountDoesNotExist and AccountRead.
 
 	statedb.IntermediateRoot(false)
 
 	cRoot := statedb.GetTrie().Hash()
 	
 	accountProof1, aNeighbourNode2, aExtNibbles2, isLastLeaf2, err := statedb.GetProof(addr)
-	check(err)
+	if err != nil {
+		return nil, err
+	}
+	// The oracle errors that occurred while the state was being modified.
+	if err := statedb.Error(); err != nil {
+		return nil, err
+	}
 
 	if tMod.Type == AccountDoesNotExist && len(accountProof) == 0 {
 		// If there is only one account in the state trie and we want to prove for some 
@@ -142,7 +284,9 @@ func obtainAccountProofAndConvertToWitness(i int, tMod TrieModification, tModsLe
 		// We get the root node (the only account) and put it as the only element of the proof,
 		// it will act as a "wrong" leaf.
 		account, err := statedb.GetTrieRootElement()
-		check(err)
+		if err != nil {
+			return nil, err
+		}
 		accountProof = make([][]byte, 1)
 		accountProof[0] = account
 		accountProof1 = make([][]byte, 1)
@@ -168,6 +312,10 @@ func obtainAccountProofAndConvertToWitness(i int, tMod TrieModification, tModsLe
 		proofType = "AccountDoesNotExist"
 	} else if tMod.Type == CodeHashChanged {
 		proofType = "CodeHashExists" // TODO: change when it changes in the circuit
+	} else if tMod.Type == StorageWiped {
+		proofType = "StorageWiped"
+	} else if tMod.Type == AccountRead {
+		proofType = "AccountRead"
 	}
 		
 	nodes = append(nodes, GetStartNode(proofType, sRoot, cRoot))
@@ -177,22 +325,39 @@ func obtainAccountProofAndConvertToWitness(i int, tMod TrieModification, tModsLe
 	nodes = append(nodes, nodesAccount...)
 	nodes = append(nodes, GetEndNode())
 
-	return nodes
+	return nodes, nil
 }
 
 // obtainTwoProofsAndConvertToWitness obtains the GetProof proof before and after the modification for each
 // of the modification. It then converts the two proofs into an MPT circuit witness. Witness is thus
 // prepared for each of the modifications and the witnesses are chained together - the final root of
 // the previous witness is the same as the start root of the current witness.
-func obtainTwoProofsAndConvertToWitness(trieModifications []TrieModification, statedb *state.StateDB, specialTest byte) []Node {
-	statedb.IntermediateRoot(false)
+//
+// With deleteEmptyObjects (EIP-161, since Spurious Dragon), an account which is empty after
+// a modification is removed: its AccountDestructed witness follows the one of the
+// modification, unless the next modification of the account destructs it. An account left
+// empty by AccountCreate is removed too, unless the next modification of the account
+// changes it.
+func obtainTwoProofsAndConvertToWitness(trieModifications []TrieModification, statedb *state.StateDB, specialTest byte, deleteEmptyObjects bool) ([]Node, error) {
+	// The keys aren't hashed in the special tests, AddressHash and KeyHash are the keys.
+	if !statedb.Db.Oracle.PreventHashingInSecureTrie() {
+		for i := range trieModifications {
+			if err := trieModifications[i].checkPreimages(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := prefetchTrieModifications(trieModifications, statedb); err != nil {
+		return nil, err
+	}
+	statedb.IntermediateRoot(deleteEmptyObjects)
 	var nodes []Node
 
 	for i := 0; i < len(trieModifications); i++ {
 		tMod := trieModifications[i]
-		if tMod.Type == StorageChanged || tMod.Type == StorageDoesNotExist {
+		if tMod.Type.isStorage() {
 			kh := crypto.Keccak256(tMod.Key.Bytes())
-			if oracle.PreventHashingInSecureTrie {
+			if statedb.Db.Oracle.PreventHashingInSecureTrie() {
 				kh = tMod.Key.Bytes()
 			}
 			keyHashed := trie.KeybytesToHex(kh)
@@ -201,17 +366,23 @@ func obtainTwoProofsAndConvertToWitness(trieModifications []TrieModification, st
 			addrh := crypto.Keccak256(addr.Bytes())
 			accountAddr := trie.KeybytesToHex(addrh)
 
-			oracle.PrefetchAccount(statedb.Db.BlockNumber, tMod.Address, nil)
-			// oracle.PrefetchStorage(statedb.Db.BlockNumber, addr, tMod.Key, nil)
+			if _, err := statedb.Db.Oracle.PrefetchAccount(statedb.Db.BlockNumber, tMod.Address, nil); err != nil {
+				return nil, err
+			}
+			// statedb.Db.Oracle.PrefetchStorage(statedb.Db.BlockNumber, addr, tMod.Key, nil)
 
 			if specialTest == 1 {
 				statedb.CreateAccount(addr)
 			}
 	
 			accountProof, aNeighbourNode1, aExtNibbles1, aIsLastLeaf1, err := statedb.GetProof(addr)
-			check(err)
+			if err != nil {
+				return nil, err
+			}
 			storageProof, neighbourNode1, extNibbles1, isLastLeaf1, err := statedb.GetStorageProof(addr, tMod.Key)
-			check(err)
+			if err != nil {
+				return nil, err
+			}
 
 			sRoot := statedb.GetTrie().Hash()
 
@@ -225,13 +396,23 @@ func obtainTwoProofsAndConvertToWitness(trieModifications []TrieModification, st
 			proofType := "StorageChanged"
 			if tMod.Type == StorageDoesNotExist {
 				proofType = "StorageDoesNotExist"
+			} else if tMod.Type == StorageRead {
+				proofType = "StorageRead"
 			}
 			
 			accountProof1, aNeighbourNode2, aExtNibbles2, aIsLastLeaf2, err := statedb.GetProof(addr)
-			check(err)
+			if err != nil {
+				return nil, err
+			}
 
 			storageProof1, neighbourNode2, extNibbles2, isLastLeaf2, err := statedb.GetStorageProof(addr, tMod.Key)
-			check(err)
+			if err != nil {
+				return nil, err
+			}
+			// The oracle errors that occurred while the storage was being read or modified.
+			if err := statedb.Error(); err != nil {
+				return nil, err
+			}
 
 			aNode := aNeighbourNode2
 			aIsLastLeaf := aIsLastLeaf1
@@ -270,18 +451,32 @@ func obtainTwoProofsAndConvertToWitness(trieModifications []TrieModification, st
 			nodes = append(nodes, nodesStorage...)
 			nodes = append(nodes, GetEndNode())
 		} else {
-			nodes = obtainAccountProofAndConvertToWitness(i, tMod, len(trieModifications), statedb, specialTest)
+			nodesAccount, err := obtainAccountProofAndConvertToWitness(i, tMod, len(trieModifications), statedb, specialTest)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, nodesAccount...)
+		}
+
+		if deleteEmptyObjects && statedb.Exist(tMod.Address) && statedb.Empty(tMod.Address) && deletesEmpty(trieModifications, i) {
+			tModDelete := TrieModification{Type: AccountDestructed, Address: tMod.Address}
+			nodesDelete, err := obtainAccountProofAndConvertToWitness(i, tModDelete, len(trieModifications), statedb, specialTest)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, nodesDelete...)
 		}
 	}
 
-	return nodes
+	return nodes, nil
 }
 
 // prepareWitness obtains the GetProof proof before and after the modification for each
 // of the modification. It then converts the two proofs into an MPT circuit witness for each of
 // the modifications and stores it into a file.
 func prepareWitness(testName string, trieModifications []TrieModification, statedb *state.StateDB) {
-	nodes := obtainTwoProofsAndConvertToWitness(trieModifications, statedb, 0)
+	nodes, err := obtainTwoProofsAndConvertToWitness(trieModifications, statedb, 0, false)
+	check(err)
 	StoreNodes(testName, nodes)
 }
 
@@ -291,7 +486,8 @@ func prepareWitness(testName string, trieModifications []TrieModification, state
 // instructs the function obtainTwoProofsAndConvertToWitness to prepare special trie states, like moving
 // the account leaf in the first trie level.
 func prepareWitnessSpecial(testName string, trieModifications []TrieModification, statedb *state.StateDB, specialTest byte) {
-	nodes := obtainTwoProofsAndConvertToWitness(trieModifications, statedb, specialTest)
+	nodes, err := obtainTwoProofsAndConvertToWitness(trieModifications, statedb, specialTest, false)
+	check(err)
 	StoreNodes(testName, nodes)
 }
 
@@ -303,9 +499,13 @@ func updateStateAndPrepareWitness(testName string, keys, values []common.Hash, a
 		trieModifications []TrieModification) {
 	blockNum := 13284469
 	blockNumberParent := big.NewInt(int64(blockNum))
-	blockHeaderParent := oracle.PrefetchBlock(blockNumberParent, true, nil)
-	database := state.NewDatabase(blockHeaderParent)
-	statedb, _ := state.New(blockHeaderParent.Root, database, nil)
+	o := oracle.NewRPCOracle(oracle.RemoteUrl)
+	blockHeaderParent, err := o.PrefetchBlock(oracle.BlockNumberRef(blockNumberParent), true, nil)
+	check(err)
+	database, err := state.NewDatabase(blockHeaderParent, o)
+	check(err)
+	statedb, err := state.New(blockHeaderParent.Root, database, nil)
+	check(err)
 
 	statedb.DisableLoadingRemoteAccounts()
 
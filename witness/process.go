@@ -0,0 +1,175 @@
+package witness
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/oracle"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/state"
+)
+
+// Withdrawal is a withdrawal from the beacon chain, the amount is in gwei.
+type Withdrawal struct {
+	Index     uint64
+	Validator uint64
+	Address   common.Address
+	Amount    uint64
+}
+
+// ProcessBlock executes the transactions of block with the go-ethereum EVM on the state
+// of parent (opened in db), then applies the block and uncle rewards (to the proof-of-work
+// blocks of the ethash chains) and withdrawals. The modifications recorded in
+// the journal after each transaction, the rewards and the withdrawals are converted into
+// a chained witness which starts at the state root of parent.
+//
+// The state root after the block must be the state root of block, otherwise a
+// *oracle.TransitionError is returned. The state is then committed to db, the next block
+// can be processed on it. getHash returns the hashes of the ancestors for BLOCKHASH, when
+// it is nil only the hash of parent is known.
+func ProcessBlock(config *params.ChainConfig, db state.Database, parent *types.Header, block *types.Block, withdrawals []Withdrawal, getHash vm.GetHashFunc) ([]Node, error) {
+	statedb, err := state.New(parent.Root, db, nil)
+	if err != nil {
+		return nil, err
+	}
+	header := block.Header()
+	if getHash == nil {
+		getHash = func(n uint64) common.Hash {
+			if n == parent.Number.Uint64() {
+				return parent.Hash()
+			}
+			return common.Hash{}
+		}
+	}
+	blockContext := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		GetHash:     getHash,
+		Coinbase:    header.Coinbase,
+		GasLimit:    header.GasLimit,
+		BlockNumber: new(big.Int).Set(header.Number),
+		Time:        new(big.Int).SetUint64(header.Time),
+		Difficulty:  new(big.Int).Set(header.Difficulty),
+		BaseFee:     header.BaseFee,
+	}
+	deleteEmptyObjects := config.IsEIP158(header.Number)
+
+	var trieModifications []TrieModification
+	// finalise takes the modifications of the last step from the journal.
+	finalise := func() error {
+		if err := statedb.Error(); err != nil {
+			return err
+		}
+		trieModifications = append(trieModifications, ModificationsFromJournal(statedb)...)
+		statedb.Finalise(deleteEmptyObjects)
+		return nil
+	}
+
+	signer := types.MakeSigner(config, header.Number)
+	gasPool := new(core.GasPool).AddGas(header.GasLimit)
+	var gasUsed uint64
+	for i, tx := range block.Transactions() {
+		msg, err := tx.AsMessage(signer, header.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d (%s): %v", i, tx.Hash(), err)
+		}
+		statedb.Prepare(tx.Hash(), i)
+		evm := vm.NewEVM(blockContext, core.NewEVMTxContext(msg), statedb, config, vm.Config{})
+		result, err := core.ApplyMessage(evm, msg, gasPool)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d (%s): %v", i, tx.Hash(), err)
+		}
+		gasUsed += result.UsedGas
+		if err := finalise(); err != nil {
+			return nil, err
+		}
+	}
+	if gasUsed != header.GasUsed {
+		return nil, fmt.Errorf("block %d: gas used %d != %d", header.Number, gasUsed, header.GasUsed)
+	}
+
+	// Only ethash pays rewards, and only before the merge (the proof-of-stake blocks have no
+	// difficulty). Clique (e.g. geth --dev) has none.
+	if config.Ethash != nil && header.Difficulty.Sign() > 0 {
+		accumulateRewards(config, statedb, header, block.Uncles())
+		if err := finalise(); err != nil {
+			return nil, err
+		}
+	}
+	if len(withdrawals) > 0 {
+		for _, w := range withdrawals {
+			amount := new(big.Int).Mul(new(big.Int).SetUint64(w.Amount), big.NewInt(params.GWei))
+			statedb.AddBalance(w.Address, amount)
+		}
+		if err := finalise(); err != nil {
+			return nil, err
+		}
+	}
+
+	if root := statedb.IntermediateRoot(deleteEmptyObjects); root != header.Root {
+		return nil, &oracle.TransitionError{BlockNumber: header.Number, Root: root, Expected: header.Root}
+	}
+
+	// The witness is generated on a separate StateDB, statedb already has the modifications.
+	witnessdb, err := state.New(parent.Root, db, nil)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := obtainTwoProofsAndConvertToWitness(trieModifications, witnessdb, 0, deleteEmptyObjects)
+	if err != nil {
+		return nil, err
+	}
+	// The witness itself has to end in the state root of block, the state of witnessdb
+	// could differ from it (e.g. IntermediateRoot would remove the empty accounts).
+	if root := finalRoot(nodes, parent.Root); root != header.Root {
+		return nil, &oracle.TransitionError{BlockNumber: header.Number, Root: root, Expected: header.Root}
+	}
+
+	if _, err := statedb.Commit(deleteEmptyObjects); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// finalRoot returns the C root of the last modification in the chained witness, start if
+// there are no modifications.
+func finalRoot(nodes []Node, start common.Hash) common.Hash {
+	root := start
+	for _, node := range nodes {
+		if node.Start != nil && node.Start.ProofType != "Disabled" {
+			root = common.BytesToHash(node.Values[1][1:33])
+		}
+	}
+	return root
+}
+
+// accumulateRewards credits the coinbase of the block with the block reward and the
+// rewards for the uncles, and the coinbases of the uncles with their rewards, as
+// ethash does.
+func accumulateRewards(config *params.ChainConfig, statedb *state.StateDB, header *types.Header, uncles []*types.Header) {
+	blockReward := ethash.FrontierBlockReward
+	if config.IsByzantium(header.Number) {
+		blockReward = ethash.ByzantiumBlockReward
+	}
+	if config.IsConstantinople(header.Number) {
+		blockReward = ethash.ConstantinopleBlockReward
+	}
+	reward := new(big.Int).Set(blockReward)
+	r := new(big.Int)
+	for _, uncle := range uncles {
+		r.Add(uncle.Number, big.NewInt(8))
+		r.Sub(r, header.Number)
+		r.Mul(r, blockReward)
+		r.Div(r, big.NewInt(8))
+		statedb.AddBalance(uncle.Coinbase, r)
+
+		r.Div(blockReward, big.NewInt(32))
+		reward.Add(reward, r)
+	}
+	statedb.AddBalance(header.Coinbase, reward)
+}
@@ -0,0 +1,205 @@
+package witness
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	gethstate "github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/oracle"
+)
+
+// TestProcessBlock processes blocks made by go-ethereum's chain maker (with the state
+// roots computed by go-ethereum) on the state built from the same genesis alloc.
+func TestProcessBlock(t *testing.T) {
+	config := params.AllEthashProtocolChanges
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	// The empty account is touched by a transfer of zero value and removed (EIP-161).
+	empty := common.HexToAddress("0x4")
+	alloc := core.GenesisAlloc{
+		sender:                      {Balance: big.NewInt(params.Ether)},
+		common.HexToAddress("0xaa"): {Balance: big.NewInt(1), Code: []byte{0x00}},
+		empty:                       {Balance: new(big.Int)},
+	}
+	genesisDb := rawdb.NewMemoryDatabase()
+	genesis := (&core.Genesis{Config: config, Alloc: alloc, GasLimit: 8000000}).MustCommit(genesisDb)
+
+	// The init code sets the slot 1 to 0x42 and deploys the code which stores the first
+	// word of the call data in the slot 0.
+	initCode := common.FromHex("6042600155" + "65600035600055600052" + "6006601af3")
+	contract := crypto.CreateAddress(sender, 1)
+	receiver := common.HexToAddress("0xbb")
+	uncleCoinbase := common.HexToAddress("0xcc")
+
+	signer := types.LatestSigner(config)
+	gasPrice := big.NewInt(10 * params.GWei)
+	sign := func(nonce uint64, to *common.Address, value int64, data []byte) *types.Transaction {
+		tx := types.NewTx(&types.LegacyTx{Nonce: nonce, To: to, Value: big.NewInt(value), Gas: 100000, GasPrice: gasPrice, Data: data})
+		signed, err := types.SignTx(tx, signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	blocks, _ := core.GenerateChain(config, genesis, ethash.NewFaker(), genesisDb, 2, func(i int, b *core.BlockGen) {
+		switch i {
+		case 0:
+			b.AddTx(sign(0, &receiver, 1000, nil))
+			b.AddTx(sign(1, nil, 0, initCode))
+		case 1:
+			b.AddTx(sign(2, &contract, 0, common.LeftPadBytes([]byte{0x07}, 32)))
+			b.AddTx(sign(3, &receiver, 5, nil))
+			b.AddTx(sign(4, &empty, 0, nil))
+			b.AddUncle(&types.Header{Number: big.NewInt(1), Coinbase: uncleCoinbase, Difficulty: big.NewInt(1)})
+		}
+	})
+
+	statedb := offlineStateDB(t, alloc)
+	if statedb.IntermediateRoot(false) != genesis.Root() {
+		t.Fatal("the state root differs from the genesis root")
+	}
+	// The proof types of the witnesses: each transaction changes the nonce and balance of
+	// the sender and the balance of the coinbase, a created account starts with
+	// AccountCreate (which has the NonceChanged proof type).
+	expectedCounts := []map[string]int{
+		// transfer to a new account, contract creation, block reward
+		{"NonceChanged": 6, "BalanceChanged": 6, "CodeHashExists": 1, "StorageChanged": 1},
+		// contract call, transfer, touch of the empty account (removed), block and uncle rewards
+		{"NonceChanged": 4, "BalanceChanged": 10, "StorageChanged": 1, "AccountDestructed": 1},
+	}
+	parent := genesis.Header()
+	for i, block := range blocks {
+		nodes, err := ProcessBlock(config, statedb.Db, parent, block, nil, nil)
+		if err != nil {
+			t.Fatalf("block %d: %v", block.NumberU64(), err)
+		}
+		checkBlockWitness(t, nodes, parent.Root, block.Root(), expectedCounts[i])
+		parent = block.Header()
+	}
+
+	// A post-merge block without transactions, only with withdrawals (no rewards). The
+	// state root is computed by go-ethereum.
+	withdrawals := []Withdrawal{
+		{Index: 0, Validator: 1, Address: receiver, Amount: 3},
+		{Index: 1, Validator: 2, Address: common.HexToAddress("0xdd"), Amount: 4},
+	}
+	expected, err := gethstate.New(parent.Root, gethstate.NewDatabase(genesisDb), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range withdrawals {
+		expected.AddBalance(w.Address, new(big.Int).Mul(new(big.Int).SetUint64(w.Amount), big.NewInt(params.GWei)))
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     big.NewInt(3),
+		Difficulty: new(big.Int),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 10,
+		BaseFee:    parent.BaseFee,
+		Root:       expected.IntermediateRoot(true),
+	}
+	nodes, err := ProcessBlock(config, statedb.Db, parent, types.NewBlockWithHeader(header), withdrawals, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBlockWitness(t, nodes, parent.Root, header.Root, map[string]int{"NonceChanged": 1, "BalanceChanged": 2})
+
+	// Without the withdrawals the root is wrong.
+	_, err = ProcessBlock(config, statedb.Db, parent, types.NewBlockWithHeader(header), nil, nil)
+	var te *oracle.TransitionError
+	if !errors.As(err, &te) || te.Expected != header.Root {
+		t.Fatalf("expected a TransitionError, got %v", err)
+	}
+}
+
+// checkBlockWitness checks that the chained witness of a block goes from the state root of
+// the parent to the state root of the block, with the given numbers of the proof types.
+func checkBlockWitness(t *testing.T, nodes []Node, parentRoot, root common.Hash, counts map[string]int) {
+	t.Helper()
+	sRoot, cRoot := witnessRoots(nodes)
+	if sRoot != parentRoot {
+		t.Fatalf("the witness starts at %s, expected the parent root %s", sRoot, parentRoot)
+	}
+	if cRoot != root {
+		t.Fatalf("the witness ends at %s, expected the block root %s", cRoot, root)
+	}
+	types := make(map[string]int)
+	for _, proofType := range proofTypes(nodes) {
+		types[proofType]++
+	}
+	if !reflect.DeepEqual(types, counts) {
+		t.Fatalf("proof types %v, expected %v", types, counts)
+	}
+}
+
+// TestProcessBlockClique processes a block of a Clique chain (like geth --dev): the block
+// has a difficulty, but there are no rewards.
+func TestProcessBlockClique(t *testing.T) {
+	config := params.AllCliqueProtocolChanges
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	receiver := common.HexToAddress("0xbb")
+	alloc := core.GenesisAlloc{
+		sender:                     {Balance: big.NewInt(params.Ether)},
+		common.HexToAddress("0x1"): {Balance: big.NewInt(1)},
+	}
+	genesisDb := rawdb.NewMemoryDatabase()
+	genesis := (&core.Genesis{Config: config, Alloc: alloc, GasLimit: 8000000, Difficulty: big.NewInt(1)}).MustCommit(genesisDb)
+	parent := genesis.Header()
+
+	for _, test := range []struct {
+		tip    *big.Int
+		counts map[string]int
+	}{
+		// The nonce and balance of the sender, the receiver and the coinbase (the zero
+		// address) are created (NonceChanged proof type) with the transferred value and
+		// the tip.
+		{big.NewInt(params.GWei), map[string]int{"NonceChanged": 3, "BalanceChanged": 3}},
+		// Without a tip the coinbase is created empty and removed again (EIP-161).
+		{new(big.Int), map[string]int{"NonceChanged": 3, "BalanceChanged": 2, "AccountDestructed": 1}},
+	} {
+		gasPrice := new(big.Int).Add(parent.BaseFee, test.tip)
+		tx, err := types.SignTx(types.NewTx(&types.LegacyTx{To: &receiver, Value: big.NewInt(1000), Gas: 21000, GasPrice: gasPrice}),
+			types.LatestSigner(config), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(1),
+			Difficulty: big.NewInt(2), // in turn
+			GasLimit:   parent.GasLimit,
+			Time:       parent.Time + 1,
+			BaseFee:    parent.BaseFee,
+		}
+		// The state root is computed by go-ethereum.
+		expected, err := gethstate.New(parent.Root, gethstate.NewDatabase(genesisDb), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := core.ApplyTransaction(config, nil, &header.Coinbase, new(core.GasPool).AddGas(header.GasLimit), expected, header, tx, &header.GasUsed, vm.Config{}); err != nil {
+			t.Fatal(err)
+		}
+		header.Root = expected.IntermediateRoot(true)
+		block := types.NewBlock(header, types.Transactions{tx}, nil, nil, trie.NewStackTrie(nil))
+
+		statedb := offlineStateDB(t, alloc)
+		nodes, err := ProcessBlock(config, statedb.Db, parent, block, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkBlockWitness(t, nodes, parent.Root, header.Root, test.counts)
+	}
+}
@@ -0,0 +1,77 @@
+package witness
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/oracle"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/state"
+)
+
+// ReadModifications returns the proofs of the state accessed but not changed by
+// trieModifications, for the addresses and slots of an EIP-2930 access list (of a
+// transaction, or StateDB.AccessList after the execution):
+//   - StorageRead (with the value) or StorageDoesNotExist for each slot of an existing
+//     account which is not changed,
+//   - AccountRead (with the nonce and the balance) for each existing account which is
+//     not changed and has no slot proofs (they prove the account too),
+//   - AccountDoesNotExist for each account which doesn't exist.
+//
+// statedb is at the state before trieModifications, the read proofs are to be put before
+// them in the witness.
+func ReadModifications(statedb *state.StateDB, accessList types.AccessList, trieModifications []TrieModification) ([]TrieModification, error) {
+	// The accounts and the slots which already have a proof (a change or a read), the
+	// access list can also have duplicates.
+	accounts := make(map[common.Address]bool)
+	slots := make(map[common.Address]map[common.Hash]bool)
+	addSlot := func(addr common.Address, key common.Hash) {
+		if slots[addr] == nil {
+			slots[addr] = make(map[common.Hash]bool)
+		}
+		slots[addr][key] = true
+	}
+	for _, tMod := range trieModifications {
+		if tMod.Type.isStorage() {
+			addSlot(tMod.Address, tMod.Key)
+		} else {
+			accounts[tMod.Address] = true
+		}
+	}
+
+	if !statedb.Db.Oracle.PreventHashingInSecureTrie() {
+		var keys []oracle.StorageKeys
+		for _, tuple := range accessList {
+			keys = append(keys, oracle.StorageKeys{Address: tuple.Address, Keys: tuple.StorageKeys})
+		}
+		if err := statedb.Db.Oracle.Prefetch(statedb.Db.BlockNumber, keys); err != nil {
+			return nil, err
+		}
+	}
+
+	var reads []TrieModification
+	for _, tuple := range accessList {
+		addr := tuple.Address
+		if !statedb.Exist(addr) {
+			if !accounts[addr] {
+				reads = append(reads, TrieModification{Type: AccountDoesNotExist, Address: addr})
+				accounts[addr] = true
+			}
+			continue
+		}
+		for _, key := range tuple.StorageKeys {
+			if slots[addr][key] {
+				continue
+			}
+			addSlot(addr, key)
+			if value := statedb.GetState(addr, key); value != (common.Hash{}) {
+				reads = append(reads, TrieModification{Type: StorageRead, Address: addr, Key: key, Value: value})
+			} else {
+				reads = append(reads, TrieModification{Type: StorageDoesNotExist, Address: addr, Key: key})
+			}
+		}
+		if !accounts[addr] && len(slots[addr]) == 0 {
+			reads = append(reads, TrieModification{Type: AccountRead, Address: addr, Nonce: statedb.GetNonce(addr), Balance: statedb.GetBalance(addr)})
+			accounts[addr] = true
+		}
+	}
+	return reads, statedb.Error()
+}
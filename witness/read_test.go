@@ -0,0 +1,80 @@
+package witness
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestReadModifications(t *testing.T) {
+	a, b, c, d := common.HexToAddress("0xa"), common.HexToAddress("0xb"), common.HexToAddress("0xc"), common.HexToAddress("0xd")
+	k1, k2, k3 := common.HexToHash("0x1"), common.HexToHash("0x2"), common.HexToHash("0x3")
+	alloc := core.GenesisAlloc{
+		a:                          {Balance: big.NewInt(1), Storage: map[common.Hash]common.Hash{k1: common.HexToHash("0x11"), k2: common.HexToHash("0x22")}},
+		b:                          {Balance: big.NewInt(2), Nonce: 3},
+		common.HexToAddress("0x1"): {Balance: big.NewInt(1)},
+	}
+
+	// The access list as recorded by the StateDB during the execution.
+	executed := offlineStateDB(t, alloc)
+	executed.AddSlotToAccessList(a, k3)
+	executed.AddSlotToAccessList(a, k1)
+	executed.AddSlotToAccessList(a, k2)
+	executed.AddAddressToAccessList(b)
+	executed.AddAddressToAccessList(c)
+	executed.AddSlotToAccessList(d, k1)
+	accessList := executed.AccessList()
+	expectedList := types.AccessList{
+		{Address: a, StorageKeys: []common.Hash{k1, k2, k3}},
+		{Address: b, StorageKeys: []common.Hash{}},
+		{Address: c, StorageKeys: []common.Hash{}},
+		{Address: d, StorageKeys: []common.Hash{k1}},
+	}
+	if !reflect.DeepEqual(accessList, expectedList) {
+		t.Fatalf("access list %+v, expected %+v", accessList, expectedList)
+	}
+
+	trieModifications := []TrieModification{
+		{Type: StorageChanged, Address: a, Key: k2, Value: common.HexToHash("0x23")},
+	}
+	statedb := offlineStateDB(t, alloc)
+	reads, err := ReadModifications(statedb, accessList, trieModifications)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []TrieModification{
+		{Type: StorageRead, Address: a, Key: k1, Value: common.HexToHash("0x11")},
+		{Type: StorageDoesNotExist, Address: a, Key: k3},
+		{Type: AccountRead, Address: b, Nonce: 3, Balance: big.NewInt(2)},
+		{Type: AccountDoesNotExist, Address: c},
+		{Type: AccountDoesNotExist, Address: d},
+	}
+	if !reflect.DeepEqual(reads, expected) {
+		t.Fatalf("read modifications %+v, expected %+v", reads, expected)
+	}
+
+	root := statedb.IntermediateRoot(false)
+	nodes, err := obtainTwoProofsAndConvertToWitness(append(reads, trieModifications...), statedb, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := proofTypes(nodes)
+	expectedTypes := []string{"StorageRead", "StorageDoesNotExist", "AccountRead", "AccountDoesNotExist", "AccountDoesNotExist", "StorageChanged"}
+	if !reflect.DeepEqual(types, expectedTypes) {
+		t.Fatalf("proof types %v, expected %v", types, expectedTypes)
+	}
+	// The reads don't change the state: S and C are the root of the state before the change.
+	for _, node := range nodes {
+		if node.Start == nil || node.Start.ProofType == "Disabled" || node.Start.ProofType == "StorageChanged" {
+			continue
+		}
+		if !bytes.Equal(node.Values[0], node.Values[1]) || !bytes.Equal(node.Values[0][1:33], root[:]) {
+			t.Fatalf("%s changes the root", node.Start.ProofType)
+		}
+	}
+}
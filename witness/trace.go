@@ -0,0 +1,103 @@
+package witness
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/oracle"
+)
+
+// ModificationsFromTrace returns the modifications of the state made by the block, as
+// reported by the prestateTracer, and prefetches the proofs (at the parent block) which
+// the witness for these modifications needs.
+func ModificationsFromTrace(o *oracle.RPCOracle, blockNumber *big.Int) ([]TrieModification, error) {
+	diffs, err := o.TracePrestateDiff(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	before, after := oracle.MergePrestateDiffs(diffs)
+	trieModifications := prestateModifications(before, after)
+
+	parent := new(big.Int).Sub(blockNumber, big.NewInt(1))
+	if err := o.Prefetch(parent, modificationKeys(trieModifications)); err != nil {
+		return nil, err
+	}
+	// The storage of a destructed account is wiped only if there is one, as in the
+	// modifications of ModificationsBetween and of the journal.
+	var modifications []TrieModification
+	for _, tMod := range trieModifications {
+		if tMod.Type == StorageWiped {
+			root, err := o.StorageRoot(parent, tMod.Address)
+			if err != nil {
+				return nil, err
+			}
+			if root == emptyRoot {
+				continue
+			}
+		}
+		modifications = append(modifications, tMod)
+	}
+	return modifications, nil
+}
+
+// prestateModifications returns the modifications which turn the accounts in before into
+// the accounts in after, sorted by the address and the storage key. A destructed account
+// is wiped and destructed, a re-created one is then created again. The wipe is kept
+// for every destructed account: the storage root isn't in the trace, the caller drops
+// the wipe of an empty storage.
+func prestateModifications(before, after map[common.Address]*oracle.PrestateAccount) []TrieModification {
+	var addrs []common.Address
+	for addr := range after {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	var trieModifications []TrieModification
+	for _, addr := range addrs {
+		pre, post := before[addr], after[addr]
+		if post == nil || post.Recreated {
+			if pre != nil {
+				// The prestateTracer reports only the touched slots, whether the storage
+				// is empty is known from the proof of the account.
+				trieModifications = append(trieModifications,
+					TrieModification{Type: StorageWiped, Address: addr},
+					TrieModification{Type: AccountDestructed, Address: addr})
+			}
+			if post == nil {
+				continue
+			}
+		}
+		if pre == nil || post.Recreated {
+			trieModifications = append(trieModifications, TrieModification{Type: AccountCreate, Address: addr})
+			// The changes are relative to the empty account.
+			var nonce uint64
+			pre = &oracle.PrestateAccount{Balance: new(hexutil.Big), Nonce: &nonce, Code: &hexutil.Bytes{}}
+		}
+
+		// The merged accounts have all fields set.
+		if *pre.Nonce != *post.Nonce {
+			trieModifications = append(trieModifications, TrieModification{Type: NonceChanged, Address: addr, Nonce: *post.Nonce})
+		}
+		if pre.Balance.ToInt().Cmp(post.Balance.ToInt()) != 0 {
+			trieModifications = append(trieModifications, TrieModification{Type: BalanceChanged, Address: addr, Balance: post.Balance.ToInt()})
+		}
+		if !bytes.Equal(*pre.Code, *post.Code) {
+			trieModifications = append(trieModifications, TrieModification{Type: CodeHashChanged, Address: addr, CodeHash: *post.Code})
+		}
+
+		var keys []common.Hash
+		for key := range post.Storage {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+		for _, key := range keys {
+			if post.Storage[key] != pre.Storage[key] {
+				trieModifications = append(trieModifications, TrieModification{Type: StorageChanged, Address: addr, Key: key, Value: post.Storage[key]})
+			}
+		}
+	}
+	return trieModifications
+}
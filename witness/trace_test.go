@@ -0,0 +1,210 @@
+package witness
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/devnode"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/oracle"
+)
+
+// traceResponse is the prestateTracer diff of block 1 with a single transaction which
+// changes 0x0a (nonce, balance, a slot changed and a slot cleared) and 0x0b (balance)
+// and creates 0x0c.
+const traceResponse = `{"jsonrpc":"2.0","id":1,"result":[
+	{"txHash":"0x0000000000000000000000000000000000000000000000000000000000000001","result":{
+		"pre":{
+			"0x000000000000000000000000000000000000000a":{"balance":"0xa","nonce":1,"storage":{
+				"0x0000000000000000000000000000000000000000000000000000000000000001":"0x0000000000000000000000000000000000000000000000000000000000000011",
+				"0x0000000000000000000000000000000000000000000000000000000000000002":"0x0000000000000000000000000000000000000000000000000000000000000022"}},
+			"0x000000000000000000000000000000000000000b":{"balance":"0x5"}},
+		"post":{
+			"0x000000000000000000000000000000000000000a":{"balance":"0x7","nonce":2,"storage":{
+				"0x0000000000000000000000000000000000000000000000000000000000000001":"0x0000000000000000000000000000000000000000000000000000000000000012"}},
+			"0x000000000000000000000000000000000000000b":{"balance":"0x8"},
+			"0x000000000000000000000000000000000000000c":{"balance":"0x1","code":"0x6001"}}}}
+]}`
+
+// traceServer answers debug_traceBlockByNumber with response and the other requests with
+// node, requests counts all requests.
+func traceServer(node *devnode.Node, response string, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		*requests++
+		var req struct {
+			Method string `json:"method"`
+		}
+		if json.Unmarshal(data, &req) == nil && req.Method == "debug_traceBlockByNumber" {
+			w.Write([]byte(response))
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(data))
+		node.ServeHTTP(w, r)
+	}))
+}
+
+func TestModificationsFromTrace(t *testing.T) {
+	a, b, c := common.HexToAddress("0xa"), common.HexToAddress("0xb"), common.HexToAddress("0xc")
+	key1, key2 := common.HexToHash("0x1"), common.HexToHash("0x2")
+	// The genesis block is the parent of the traced block.
+	node, err := devnode.New(core.GenesisAlloc{
+		a: {Balance: big.NewInt(10), Nonce: 1, Storage: map[common.Hash]common.Hash{key1: common.HexToHash("0x11"), key2: common.HexToHash("0x22")}},
+		b: {Balance: big.NewInt(5)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := 0
+	server := traceServer(node, traceResponse, &requests)
+	defer server.Close()
+
+	o := oracle.NewRPCOracle(server.URL)
+	o.CacheMode = oracle.Passthrough
+	trieModifications, err := ModificationsFromTrace(o, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []TrieModification{
+		{Type: NonceChanged, Address: a, Nonce: 2},
+		{Type: BalanceChanged, Address: a, Balance: big.NewInt(7)},
+		{Type: StorageChanged, Address: a, Key: key1, Value: common.HexToHash("0x12")},
+		{Type: StorageChanged, Address: a, Key: key2},
+		{Type: BalanceChanged, Address: b, Balance: big.NewInt(8)},
+		{Type: AccountCreate, Address: c},
+		{Type: BalanceChanged, Address: c, Balance: big.NewInt(1)},
+		{Type: CodeHashChanged, Address: c, CodeHash: []byte{0x60, 0x01}},
+	}
+	if len(trieModifications) != len(expected) {
+		t.Fatalf("expected %d modifications, got %d: %+v", len(expected), len(trieModifications), trieModifications)
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(trieModifications[i], expected[i]) {
+			t.Errorf("modification %d is %+v, expected %+v", i, trieModifications[i], expected[i])
+		}
+	}
+
+	// The proofs have been prefetched.
+	n := requests
+	if _, err := o.PrefetchStorage(big.NewInt(0), a, key2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if requests != n {
+		t.Error("the storage proof was not prefetched")
+	}
+}
+
+// resurrectTraceResponse is the prestateTracer diff of block 1 with two transactions: the
+// first one destructs destructAddr (its balance goes to 0x01), the second one creates it
+// again with a storage slot.
+const resurrectTraceResponse = `{"jsonrpc":"2.0","id":1,"result":[
+	{"txHash":"0x0000000000000000000000000000000000000000000000000000000000000001","result":{
+		"pre":{
+			"0x50efbf12580138bc623c95757286df4e24eb81c9":{"balance":"0xa","nonce":1},
+			"0x0000000000000000000000000000000000000001":{"balance":"0x1"}},
+		"post":{
+			"0x0000000000000000000000000000000000000001":{"balance":"0xb"}}}},
+	{"txHash":"0x0000000000000000000000000000000000000000000000000000000000000002","result":{
+		"pre":{},
+		"post":{
+			"0x50efbf12580138bc623c95757286df4e24eb81c9":{"balance":"0x5","storage":{
+				"0x0000000000000000000000000000000000000000000000000000000000000003":"0x0000000000000000000000000000000000000000000000000000000000000033"}}}}}
+]}`
+
+func TestModificationsFromTraceResurrected(t *testing.T) {
+	node, err := devnode.New(destructAlloc())
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := 0
+	server := traceServer(node, resurrectTraceResponse, &requests)
+	defer server.Close()
+
+	o := oracle.NewRPCOracle(server.URL)
+	o.CacheMode = oracle.Passthrough
+	trieModifications, err := ModificationsFromTrace(o, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The slots of the destructed account are wiped before it is created again.
+	expected := []TrieModification{
+		{Type: BalanceChanged, Address: common.HexToAddress("0x1"), Balance: big.NewInt(11)},
+		{Type: StorageWiped, Address: destructAddr},
+		{Type: AccountDestructed, Address: destructAddr},
+		{Type: AccountCreate, Address: destructAddr},
+		{Type: BalanceChanged, Address: destructAddr, Balance: big.NewInt(5)},
+		{Type: StorageChanged, Address: destructAddr, Key: destructKeys[2], Value: common.HexToHash("0x33")},
+	}
+	if !reflect.DeepEqual(trieModifications, expected) {
+		t.Fatalf("modifications %+v, expected %+v", trieModifications, expected)
+	}
+
+	statedb := offlineStateDB(t, destructAlloc())
+	if _, err := obtainTwoProofsAndConvertToWitness(trieModifications, statedb, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	alloc := destructAlloc()
+	alloc[common.HexToAddress("0x1")] = core.GenesisAccount{Balance: big.NewInt(11)}
+	alloc[destructAddr] = core.GenesisAccount{
+		Balance: big.NewInt(5),
+		Storage: map[common.Hash]common.Hash{destructKeys[2]: common.HexToHash("0x33")},
+	}
+	if statedb.IntermediateRoot(false) != offlineStateDB(t, alloc).IntermediateRoot(false) {
+		t.Fatal("the state root after the witness differs from the state with the new account")
+	}
+}
+
+// destructTraceResponse is the prestateTracer diff of block 1 with a transaction which
+// destructs 0x03 (an account without storage), its balance goes to 0x01.
+const destructTraceResponse = `{"jsonrpc":"2.0","id":1,"result":[
+	{"txHash":"0x0000000000000000000000000000000000000000000000000000000000000001","result":{
+		"pre":{
+			"0x0000000000000000000000000000000000000003":{"balance":"0x3"},
+			"0x0000000000000000000000000000000000000001":{"balance":"0x1"}},
+		"post":{
+			"0x0000000000000000000000000000000000000001":{"balance":"0x4"}}}}
+]}`
+
+func TestModificationsFromTraceDestructedWithoutStorage(t *testing.T) {
+	node, err := devnode.New(destructAlloc())
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := 0
+	server := traceServer(node, destructTraceResponse, &requests)
+	defer server.Close()
+
+	o := oracle.NewRPCOracle(server.URL)
+	o.CacheMode = oracle.Passthrough
+	trieModifications, err := ModificationsFromTrace(o, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The account has no storage, so there is nothing to wipe.
+	expected := []TrieModification{
+		{Type: BalanceChanged, Address: common.HexToAddress("0x1"), Balance: big.NewInt(4)},
+		{Type: AccountDestructed, Address: common.HexToAddress("0x3")},
+	}
+	if !reflect.DeepEqual(trieModifications, expected) {
+		t.Fatalf("modifications %+v, expected %+v", trieModifications, expected)
+	}
+
+	statedb := offlineStateDB(t, destructAlloc())
+	if _, err := obtainTwoProofsAndConvertToWitness(trieModifications, statedb, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	alloc := destructAlloc()
+	alloc[common.HexToAddress("0x1")] = core.GenesisAccount{Balance: big.NewInt(4)}
+	delete(alloc, common.HexToAddress("0x3"))
+	if statedb.IntermediateRoot(false) != offlineStateDB(t, alloc).IntermediateRoot(false) {
+		t.Fatal("the state root after the witness differs from the state without the account")
+	}
+}
@@ -4,25 +4,40 @@ import "C"
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
+	"os"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/privacy-scaling-explorations/mpt-witness-generator/oracle"
 	"github.com/privacy-scaling-explorations/mpt-witness-generator/witness"
 )
 
 type Config struct {
 	NodeUrl string `json:"NodeUrl"`
 	BlockNum int `json:"BlockNum"`
+	// Block, when set, is used instead of BlockNum: a number, a tag ("finalized"), a block
+	// hash or {"blockHash": ..., "requireCanonical": ...}.
+	Block *oracle.BlockRef `json:"Block"`
 	Addr string `json:"Addr"`
 	Keys []string `json:"Keys"`
 	Values []string `json:"Values"`
+	// StateOverride, when set, is applied to the state of the block before the
+	// modifications, in the format of the state override set of eth_call.
+	StateOverride witness.StateOverride `json:"StateOverride"`
+	// DeleteEmptyObjects removes the accounts emptied by the modifications (EIP-161).
+	DeleteEmptyObjects bool `json:"DeleteEmptyObjects"`
 }
 
 //export GetWitness
 func GetWitness(proofConf *C.char) *C.char {
 	var config Config
 
-	err := json.Unmarshal([]byte(C.GoString(proofConf)), &config)
-	fmt.Println(err)
+	// A malformed request (e.g. an invalid StateOverride) would give a witness for
+	// another state, it is rejected.
+	if err := json.Unmarshal([]byte(C.GoString(proofConf)), &config); err != nil {
+		return errorJSON(err)
+	}
 	fmt.Println(config)
 
 	trieModifications := []witness.TrieModification{}
@@ -38,7 +53,27 @@ func GetWitness(proofConf *C.char) *C.char {
 		trieModifications = append(trieModifications, trieMod)
 	}
 
-	proof := witness.GetWitness(config.NodeUrl, config.BlockNum, trieModifications)
+	block := oracle.BlockNumberRef(big.NewInt(int64(config.BlockNum)))
+	if config.Block != nil {
+		block = *config.Block
+	}
+	// The preimage store in MPT_ORACLE_PREIMAGE_DIR (if set) is opened by the first call
+	// and shared by the later ones.
+	opts := []witness.Option{
+		witness.WithStateOverride(config.StateOverride),
+		witness.WithDeleteEmptyObjects(config.DeleteEmptyObjects),
+	}
+	store, err := envPreimageStore()
+	if err != nil {
+		return errorJSON(err)
+	}
+	if store != nil {
+		opts = append(opts, witness.WithOracleOptions(oracle.WithPreimageStore(store)))
+	}
+	proof, err := witness.GetWitness(config.NodeUrl, block, trieModifications, opts...)
+	if err != nil {
+		return errorJSON(err)
+	}
 	b, err := json.Marshal(proof)
 	if err != nil {
 		fmt.Println(err)
@@ -47,4 +82,58 @@ func GetWitness(proofConf *C.char) *C.char {
 	return C.CString(string(b))
 }
 
+// The environment variable with the directory of the LevelDB preimage store.
+const preimageDirEnv = "MPT_ORACLE_PREIMAGE_DIR"
+
+// preimageStore is the store in MPT_ORACLE_PREIMAGE_DIR, opened by the first call which
+// needs it: LevelDB can't be opened twice, so the calls share it.
+var (
+	preimageStore     oracle.PreimageStore
+	preimageStoreLock sync.Mutex
+)
+
+// envPreimageStore returns the store in MPT_ORACLE_PREIMAGE_DIR, nil if the variable
+// isn't set. The oracles are given it with oracle.WithPreimageStore, they don't close it.
+func envPreimageStore() (oracle.PreimageStore, error) {
+	preimageStoreLock.Lock()
+	defer preimageStoreLock.Unlock()
+	if preimageStore == nil {
+		dir := os.Getenv(preimageDirEnv)
+		if dir == "" {
+			return nil, nil
+		}
+		store, err := oracle.OpenLevelDBPreimageStore(dir, false)
+		if err != nil {
+			return nil, err
+		}
+		preimageStore = store
+	}
+	return preimageStore, nil
+}
+
+// ClosePreimageStore closes the store in MPT_ORACLE_PREIMAGE_DIR, it is to be called
+// after the last GetWitness call (a later call opens the store again). It returns NULL,
+// or the JSON object with the error.
+//export ClosePreimageStore
+func ClosePreimageStore() *C.char {
+	preimageStoreLock.Lock()
+	defer preimageStoreLock.Unlock()
+	if preimageStore == nil {
+		return nil
+	}
+	err := preimageStore.Close()
+	preimageStore = nil
+	if err != nil {
+		return errorJSON(err)
+	}
+	return nil
+}
+
+// errorJSON returns the JSON object with the error which the caller gets instead of
+// the list of nodes.
+func errorJSON(err error) *C.char {
+	b, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return C.CString(string(b))
+}
+
 func main() {}